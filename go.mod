@@ -1,8 +1,10 @@
 module github.com/HAECHI-LABS/vault-gpg-plugin
 
-go 1.15
+go 1.16
 
 require (
+	github.com/hashicorp/go-cleanhttp v0.5.1
+	github.com/hashicorp/go-hclog v0.8.0
 	github.com/hashicorp/vault/api v1.0.4
 	github.com/hashicorp/vault/sdk v0.1.13
 	github.com/securego/gosec v0.0.0-20200401082031-e946c8c39989
@@ -1,15 +1,34 @@
 package gpg
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 // Factory gives a configured logical.Backend for the GPG plugin
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	if conf == nil {
+		return nil, errNilBackendConfig
+	}
+	if conf.StorageView == nil {
+		return nil, errNilStorageView
+	}
 	b := Backend()
 	if err := b.Setup(ctx, conf); err != nil {
 		return nil, err
@@ -23,16 +42,34 @@ func Backend() *backend {
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
 		Paths: []*framework.Path{
+			pathSearchKeys(&b),
 			pathKeys(&b),
+			pathKeysConfig(&b),
+			pathCompatCheck(&b),
+			pathKeysWipe(&b),
+			pathKeysImportSubkey(&b),
+			pathKeysSubkeys(&b),
+			pathKeysSubkeyExtendExpiry(&b),
+			pathKeysSignKey(&b),
+			pathKeysRevokeCertification(&b),
 			pathListKeys(&b),
 			pathExportKeys(&b),
+			pathPublicKey(&b),
 			pathSign(&b),
 			pathVerify(&b),
+			pathVerifyClearsign(&b),
 			pathEncrypt(&b),
 			pathDecrypt(&b),
 			pathShowSessionKey(&b),
+			pathInfo(&b),
+			pathConfig(&b),
+			pathConfigAllowedAlgorithms(&b),
+			pathWKD(&b),
 		},
 		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"wkd/*",
+			},
 			SealWrapStorage: []string{
 				"key/",
 			},
@@ -41,15 +78,118 @@ func Backend() *backend {
 		BackendType: logical.TypeLogical,
 	}
 	b.keyLocks = locksutil.CreateLocks()
+	b.wkdClient = cleanhttp.DefaultClient()
+	b.wkdCache = make(map[string]wkdCacheEntry)
 	return &b
 }
 
 type backend struct {
 	*framework.Backend
-	keyLocks []*locksutil.LockEntry
+	keyLocks     []*locksutil.LockEntry
+	keyCountLock sync.Mutex
+	configLock   sync.Mutex
+
+	wkdClient    *http.Client
+	wkdCache     map[string]wkdCacheEntry
+	wkdCacheLock sync.RWMutex
+}
+
+// logOperationFailure logs a failed cryptographic operation. Only metadata of the
+// request is logged, the processed data and the key material must never be part
+// of the log entry.
+func (b *backend) logOperationFailure(req *logical.Request, operation, keyName, errorCode string) {
+	b.Logger().Warn(operation+" failed", "key_name", keyName, "error_code", errorCode, "request_id", req.ID)
+}
+
+// Key events logged by logKeyEvent.
+const (
+	keyEventAccessed = "key.accessed"
+	keyEventDeleted  = "key.deleted"
+)
+
+// logKeyEvent logs a structured event about the private key material of a named
+// key, so that monitoring systems can follow its use beyond the audit log of
+// Vault. The SDK used by the plugin has no event system to emit it to.
+func (b *backend) logKeyEvent(req *logical.Request, eventType, keyName string) {
+	b.Logger().Info("key event", "event_type", eventType, "key_name", keyName, "operation", req.Operation, "path", req.Path, "request_id", req.ID)
+}
+
+// readArmoredKey returns the first entity of an ASCII-armored key ring. Unlike
+// openpgp.ReadArmoredKeyRing, an error is returned when the key ring is empty.
+func readArmoredKey(armored string) (*openpgp.Entity, error) {
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, err
+	}
+	if len(el) == 0 {
+		return nil, errNoKeyFound
+	}
+	return el[0], nil
+}
+
+var errNoKeyFound = errors.New("no GPG key has been found")
+
+var errNilBackendConfig = errors.New("the backend configuration is required")
+
+var errNilStorageView = errors.New("the backend configuration has no storage view")
+
+// decodeBase64 decodes the base64 value of a request field. The decoding error
+// is wrapped so it can be inspected with errors.As.
+func decodeBase64(field, value string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s as base64: %w", field, err)
+	}
+	return decoded, nil
+}
+
+// firstPacket decodes only the first packet of an encoded OpenPGP message or
+// signature.
+func firstPacket(encoded []byte, format string) (packet.Packet, error) {
+	var r io.Reader
+	switch format {
+	case "ascii-armor":
+		block, err := armor.Decode(bytes.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		r = block.Body
+	default:
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(encoded))
+	}
+
+	return packet.NewReader(r).Next()
+}
+
+// firstPacketKeyID returns the ID of the key referenced by
+// the first packet of an encoded OpenPGP message or signature: the recipient key
+// of an encrypted session key or the issuer of a signature.
+func firstPacketKeyID(encoded []byte, format string) (uint64, error) {
+	p, err := firstPacket(encoded, format)
+	if err != nil {
+		return 0, err
+	}
+	var keyID uint64
+	switch p := p.(type) {
+	case *packet.EncryptedKey:
+		keyID = p.KeyId
+	case *packet.Signature:
+		if p.IssuerKeyId == nil {
+			return 0, fmt.Errorf("signature has no issuer key ID")
+		}
+		keyID = *p.IssuerKeyId
+	case *packet.SignatureV3:
+		keyID = p.IssuerKeyId
+	default:
+		return 0, fmt.Errorf("unexpected %T packet", p)
+	}
+	return keyID, nil
 }
 
 const backendHelp = `
 The GPG backend handles GPG operations on data in-transit.
 Data sent to the backend are not stored.
+
+All paths except the Web Key Directory ones require a valid Vault token
+with a policy granting the capabilities documented in the help of each path.
 `
@@ -0,0 +1,154 @@
+//go:build integration
+// +build integration
+
+package gpg
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// TestIntegration_VaultServer runs a key lifecycle through the HTTP API of a
+// Vault server in dev mode, with the plugin binary built from the sources
+// registered from its plugin directory. It requires the vault binary in the
+// PATH and is run with:
+//
+//	go test -tags integration -run TestIntegration ./gpg
+func TestIntegration_VaultServer(t *testing.T) {
+	vaultPath, err := exec.LookPath("vault")
+	if err != nil {
+		t.Skip("vault binary not found in the PATH")
+	}
+
+	pluginDir, err := os.MkdirTemp("", "vault-gpg-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pluginDir)
+	build := exec.Command("go", "build", "-o", filepath.Join(pluginDir, "vault-gpg-plugin"), "../cmd")
+	build.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("unable to build the plugin: %s\n%s", err, out)
+	}
+
+	address := testFreeAddress(t)
+	server := exec.Command(vaultPath, "server", "-dev",
+		"-dev-root-token-id=root",
+		"-dev-listen-address="+address,
+		"-dev-plugin-dir="+pluginDir)
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		server.Process.Kill()
+		server.Wait()
+	}()
+
+	config := api.DefaultConfig()
+	config.Address = "http://" + address
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("root")
+	testWaitForVault(t, client)
+
+	if err := client.Sys().Mount("gpg", &api.MountInput{Type: "vault-gpg-plugin"}); err != nil {
+		t.Fatal(err)
+	}
+	vault := client.Logical()
+
+	if _, err := vault.Write("gpg/keys/test", map[string]interface{}{"real_name": "Vault GPG test"}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := vault.Read("gpg/keys/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"fingerprint", "public_key", "exportable", "key_id_long"} {
+		if _, ok := key.Data[field]; !ok {
+			t.Fatalf("no %s found in the key data %#v", field, key.Data)
+		}
+	}
+
+	secret, err := vault.List("gpg/keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := secret.Data["keys"].([]interface{}); len(keys) != 1 || keys[0] != "test" {
+		t.Fatalf("expected the key test to be listed, got: %#v", keys)
+	}
+
+	secret, err = vault.Write("gpg/encrypt/test", map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": key.Data["public_key"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = vault.Write("gpg/decrypt/test", map[string]interface{}{
+		"ciphertext": secret.Data["ciphertext"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["plaintext"] != "QWxwYWNhcwo=" {
+		t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %v", secret.Data["plaintext"])
+	}
+
+	secret, err = vault.Write("gpg/sign/test", map[string]interface{}{"input": "QWxwYWNhcwo="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = vault.Write("gpg/verify/test", map[string]interface{}{
+		"input":     "QWxwYWNhcwo=",
+		"signature": secret.Data["signature"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["valid"] != true {
+		t.Fatalf("expected a valid signature, got: %#v", secret.Data)
+	}
+
+	if _, err := vault.Delete("gpg/keys/test"); err != nil {
+		t.Fatal(err)
+	}
+	secret, err = vault.Read("gpg/keys/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected the key to be deleted, got: %#v", secret)
+	}
+}
+
+// testFreeAddress returns a local address with a port that is free to listen on.
+func testFreeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// testWaitForVault waits for the dev server to be unsealed and ready to serve
+// requests.
+func testWaitForVault(t *testing.T, client *api.Client) {
+	var lastErr error
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		health, err := client.Sys().Health()
+		if err == nil && health.Initialized && !health.Sealed {
+			return
+		}
+		lastErr = err
+	}
+	t.Fatalf("the vault server is not ready: %v", lastErr)
+}
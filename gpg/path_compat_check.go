@@ -0,0 +1,74 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathCompatCheck(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/compat-check",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathCompatCheckRead,
+			},
+		},
+		HelpSynopsis:    pathCompatCheckHelpSyn,
+		HelpDescription: pathCompatCheckHelpDesc,
+	}
+}
+
+func (b *backend) pathCompatCheckRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	warnings := []string{}
+	requiredActions := []string{}
+
+	if entry.Version < keyEntryVersion {
+		warnings = append(warnings, fmt.Sprintf("the key has been stored by an older version of the plugin (storage version %d, current is %d), updating its configuration upgrades it", entry.Version, keyEntryVersion))
+	}
+
+	entity, err := b.entity(entry)
+	if err != nil {
+		requiredActions = append(requiredActions, fmt.Sprintf("the key cannot be parsed (%s), it must be deleted and imported again", err))
+	} else {
+		now := time.Now()
+		if !hasSigningKey(entity, now) {
+			warnings = append(warnings, "the key has no signing key, sign requests will be rejected")
+		}
+		if !hasEncryptionKey(entity, now, true) {
+			warnings = append(warnings, "the key has no usable encryption key, messages cannot be encrypted to it")
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"warnings":         warnings,
+			"required_actions": requiredActions,
+		},
+	}, nil
+}
+
+const pathCompatCheckHelpSyn = "Check the compatibility of a named GPG key with this version of the plugin"
+const pathCompatCheckHelpDesc = `
+This path runs a series of checks against a named GPG key, for example a key
+stored by an older version of the plugin. The returned required actions must
+be taken before the key can be used. It requires the "read" capability.
+`
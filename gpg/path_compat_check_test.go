@@ -0,0 +1,86 @@
+package gpg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestGPG_CompatCheck(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeEntry := func(name string, entry *keyEntry) {
+		storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Put(context.Background(), storageEntry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storeEntry("old", &keyEntry{SerializedKey: current.SerializedKey})
+	storeEntry("corrupted", &keyEntry{Version: keyEntryVersion, SerializedKey: []byte("corrupted")})
+
+	compatCheck := func(name string, nbWarnings, nbRequiredActions int) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + name + "/compat-check",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.IsError() {
+			t.Fatalf("not expected response: %#v", resp)
+		}
+		warnings := resp.Data["warnings"].([]string)
+		if len(warnings) != nbWarnings {
+			t.Fatalf("expected %d warnings, got: %#v", nbWarnings, warnings)
+		}
+		requiredActions := resp.Data["required_actions"].([]string)
+		if len(requiredActions) != nbRequiredActions {
+			t.Fatalf("expected %d required actions, got: %#v", nbRequiredActions, requiredActions)
+		}
+	}
+
+	compatCheck("test", 0, 0)
+	compatCheck("old", 1, 0)
+	compatCheck("corrupted", 0, 1)
+
+	// Updating the configuration upgrades the storage version
+	req.Path = "keys/old/config"
+	req.Data = map[string]interface{}{}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compatCheck("old", 0, 0)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/doNotExist/compat-check",
+	})
+	if !(resp == nil && err == nil) {
+		t.Fatal("Key does not exist but does not return not found")
+	}
+}
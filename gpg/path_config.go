@@ -0,0 +1,237 @@
+package gpg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"allow_expired_key_decryption": {
+				Type:         framework.TypeBool,
+				Description:  "Allows the decryption of messages with keys whose primary key has expired, to recover the data encrypted before their expiry.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Allow expired key decryption", Group: "Key Settings"},
+			},
+			"allow_legacy_key_types": {
+				Type:         framework.TypeBool,
+				Description:  "Allows the import of DSA primary keys and ElGamal subkeys, for compatibility with legacy PGP keys. Their generation is not supported.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Allow legacy key types", Group: "Key Settings"},
+			},
+			"debug_mode": {
+				Type:         framework.TypeBool,
+				Description:  "Accepts the time_override field of the sign and encrypt paths, to produce deterministic output in tests. Must not be enabled in production.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Debug mode", Group: "Key Settings"},
+			},
+			"enable_wkd_lookup": {
+				Type:         framework.TypeBool,
+				Description:  "Enables the discovery of recipient keys using the Web Key Directory when only the email of the recipient is provided.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Enable WKD lookup", Group: "Key Settings"},
+			},
+			"key_name_pattern": {
+				Type:         framework.TypeString,
+				Description:  "Regular expression the names of the created GPG keys must match. Defaults to any name.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name pattern", Group: "Key Settings"},
+			},
+			"max_key_count": {
+				Type:         framework.TypeInt,
+				Description:  "Maximum number of named GPG keys that can be created. Defaults to 0, meaning no limit.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Maximum key count", Group: "Key Settings"},
+			},
+			"max_plaintext_bytes": {
+				Type:         framework.TypeInt,
+				Description:  "Maximum size in bytes of the plaintexts to encrypt. Defaults to 0, meaning no limit.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Maximum plaintext bytes", Group: "Key Settings"},
+			},
+			"recipient_key_min_bits": {
+				Type:         framework.TypeInt,
+				Description:  "Minimum bit length of the primary key of the recipients of the encrypt path, which can be overridden for each request. Defaults to 0, meaning no minimum.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Recipient key minimum bits", Group: "Key Settings"},
+			},
+			"wkd_domain": {
+				Type:         framework.TypeString,
+				Description:  "Domain of the emails whose keys are served by the unauthenticated wkd path. Defaults to empty, meaning the wkd path is disabled.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "WKD domain", Group: "Key Settings"},
+			},
+			"require_mdc": {
+				Type:         framework.TypeBool,
+				Default:      true,
+				Description:  "Refuses to decrypt the messages that are not protected by a Modification Detection Code. Defaults to true.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Require MDC", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigDelete,
+			},
+		},
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) config(ctx context.Context, s logical.Storage) (*configEntry, error) {
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+
+	var result configEntry
+	if entry == nil {
+		return &result, nil
+	}
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allow_expired_key_decryption": config.AllowExpiredKeyDecryption,
+			"allow_legacy_key_types":       config.AllowLegacyKeyTypes,
+			"debug_mode":                   config.DebugMode,
+			"enable_wkd_lookup":            config.EnableWKDLookup,
+			"key_name_pattern":             config.KeyNamePattern,
+			"max_key_count":                config.MaxKeyCount,
+			"max_plaintext_bytes":          config.MaxPlaintextBytes,
+			"recipient_key_min_bits":       config.RecipientKeyMinBits,
+			"require_mdc":                  !config.AllowMissingMDC,
+			"wkd_domain":                   config.WKDDomain,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if allowExpiredKeyDecryption, ok := data.GetOk("allow_expired_key_decryption"); ok {
+		config.AllowExpiredKeyDecryption = allowExpiredKeyDecryption.(bool)
+	}
+	if allowLegacyKeyTypes, ok := data.GetOk("allow_legacy_key_types"); ok {
+		config.AllowLegacyKeyTypes = allowLegacyKeyTypes.(bool)
+	}
+	if debugMode, ok := data.GetOk("debug_mode"); ok {
+		config.DebugMode = debugMode.(bool)
+	}
+	if enableWKDLookup, ok := data.GetOk("enable_wkd_lookup"); ok {
+		config.EnableWKDLookup = enableWKDLookup.(bool)
+	}
+	if keyNamePattern, ok := data.GetOk("key_name_pattern"); ok {
+		if _, err := regexp.Compile(keyNamePattern.(string)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid key_name_pattern: %s", err)), nil
+		}
+		config.KeyNamePattern = keyNamePattern.(string)
+	}
+	if maxKeyCount, ok := data.GetOk("max_key_count"); ok {
+		if maxKeyCount.(int) < 0 {
+			return logical.ErrorResponse("max_key_count must be positive"), nil
+		}
+		config.MaxKeyCount = maxKeyCount.(int)
+	}
+	if maxPlaintextBytes, ok := data.GetOk("max_plaintext_bytes"); ok {
+		if maxPlaintextBytes.(int) < 0 {
+			return logical.ErrorResponse("max_plaintext_bytes must be positive"), nil
+		}
+		config.MaxPlaintextBytes = maxPlaintextBytes.(int)
+	}
+	if recipientKeyMinBits, ok := data.GetOk("recipient_key_min_bits"); ok {
+		if recipientKeyMinBits.(int) < 0 {
+			return logical.ErrorResponse("recipient_key_min_bits must be positive"), nil
+		}
+		config.RecipientKeyMinBits = recipientKeyMinBits.(int)
+	}
+	if requireMDC, ok := data.GetOk("require_mdc"); ok {
+		config.AllowMissingMDC = !requireMDC.(bool)
+	}
+	if wkdDomain, ok := data.GetOk("wkd_domain"); ok {
+		config.WKDDomain = strings.ToLower(wkdDomain.(string))
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathConfigDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	if err := req.Storage.Delete(ctx, "config"); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+type configEntry struct {
+	EnableWKDLookup           bool
+	MaxPlaintextBytes         int
+	AllowedAlgorithms         []string
+	RecipientKeyMinBits       int
+	AllowLegacyKeyTypes       bool
+	DebugMode                 bool
+	MaxKeyCount               int
+	KeyNamePattern            string
+	AllowExpiredKeyDecryption bool
+	WKDDomain                 string
+	// AllowMissingMDC is the negation of require_mdc so that the MDC is
+	// required by the configurations stored before its introduction.
+	AllowMissingMDC bool
+}
+
+// timeOverride returns the clock fixed to the time_override field of the
+// request, or nil when the field is not set.
+func timeOverride(config *configEntry, data *framework.FieldData) (func() time.Time, error) {
+	raw := data.Get("time_override").(string)
+	if raw == "" {
+		return nil, nil
+	}
+	if !config.DebugMode {
+		return nil, errors.New("time_override requires debug_mode to be enabled in the backend configuration")
+	}
+	override, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse time_override as RFC 3339: %w", err)
+	}
+	return func() time.Time { return override }, nil
+}
+
+const pathConfigHelpSyn = "Configure the GPG backend"
+const pathConfigHelpDesc = `
+This path is used to configure the behavior of the GPG backend shared
+by all the named GPG keys. Reading the configuration requires the "read"
+capability, updating it the "update" capability and resetting it to the
+defaults, including the allowed algorithms, the "delete" capability.
+`
@@ -0,0 +1,97 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigAllowedAlgorithms(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/allowed-algorithms/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"algorithms": {
+				Type: framework.TypeStringSlice,
+				Description: `Hash algorithms allowed by the sign and encrypt operations. Valid values are:
+
+* sha2-224
+* sha2-256
+* sha2-384
+* sha2-512
+
+Defaults to all algorithms.`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Algorithms", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigAllowedAlgorithmsRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigAllowedAlgorithmsWrite,
+			},
+		},
+		HelpSynopsis:    pathConfigAllowedAlgorithmsHelpSyn,
+		HelpDescription: pathConfigAllowedAlgorithmsHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigAllowedAlgorithmsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"algorithms": config.AllowedAlgorithms,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigAllowedAlgorithmsWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithms := strutil.RemoveDuplicates(data.Get("algorithms").([]string), true)
+	for _, algorithm := range algorithms {
+		if !strutil.StrListContains(supportedAlgorithms, algorithm) {
+			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+		}
+	}
+	config.AllowedAlgorithms = algorithms
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// algorithmAllowed reports whether the hash algorithm is allowed by the
+// configuration of the backend.
+func algorithmAllowed(config *configEntry, algorithm string) bool {
+	if len(config.AllowedAlgorithms) == 0 {
+		return true
+	}
+	return strutil.StrListContains(config.AllowedAlgorithms, algorithm)
+}
+
+const pathConfigAllowedAlgorithmsHelpSyn = "Restrict the hash algorithms usable by all the named GPG keys"
+const pathConfigAllowedAlgorithmsHelpDesc = `
+This path is used to configure the hash algorithms the sign and encrypt
+operations accept, whatever the named GPG key used. An empty list allows
+all the supported algorithms. Reading the configuration requires the
+"read" capability and updating it the "update" capability.
+`
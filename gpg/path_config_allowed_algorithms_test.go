@@ -0,0 +1,116 @@
+package gpg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestGPG_ConfigAllowedAlgorithms(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	writeConfig := func(algorithms []string, errExpected bool) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "config/allowed-algorithms",
+			Data: map[string]interface{}{
+				"algorithms": algorithms,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errExpected {
+			if !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	readConfig := func(expected []string) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "config/allowed-algorithms",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.IsError() {
+			t.Fatalf("not expected response: %#v", resp)
+		}
+		algorithms := resp.Data["algorithms"].([]string)
+		if len(algorithms) != 0 || len(expected) != 0 {
+			if !reflect.DeepEqual(algorithms, expected) {
+				t.Fatalf("expected algorithms %v, got: %v", expected, algorithms)
+			}
+		}
+	}
+
+	operation := func(path string, data map[string]interface{}, permitted bool) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if permitted {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsError() {
+				t.Fatalf("%s: not expected error response: %#v", path, *resp)
+			}
+			return
+		}
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("%s: expected invalid request error, got: %v", path, err)
+		}
+		if !resp.IsError() || resp.Error().Error() != "algorithm not permitted by policy" {
+			t.Fatalf("%s: expected policy error response: %#v", path, resp)
+		}
+	}
+	sign := map[string]interface{}{"input": "QWxwYWNhcwo="}
+	encrypt := map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey}
+
+	readConfig(nil)
+	operation("sign/test", sign, true)
+	operation("encrypt/test", encrypt, true)
+
+	writeConfig([]string{"sha2-512", "sha2-384"}, false)
+	readConfig([]string{"sha2-384", "sha2-512"})
+	operation("sign/test", sign, false)
+	operation("sign/test/sha2-512", sign, true)
+	operation("encrypt/test", encrypt, false)
+	operation("encrypt/test/sha2-384", encrypt, true)
+
+	writeConfig([]string{"sha1"}, true)
+	readConfig([]string{"sha2-384", "sha2-512"})
+
+	writeConfig([]string{}, false)
+	readConfig(nil)
+	operation("sign/test", sign, true)
+	operation("encrypt/test", encrypt, true)
+}
@@ -0,0 +1,223 @@
+package gpg
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestGPG_Config(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	readConfig := func(expected map[string]interface{}) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "config",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.IsError() {
+			t.Fatalf("not expected response: %#v", resp)
+		}
+		for field, value := range expected {
+			if resp.Data[field] != value {
+				t.Fatalf("expected %s to be %v, got: %v", field, value, resp.Data[field])
+			}
+		}
+	}
+
+	writeConfig := func(data map[string]interface{}) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	readConfig(map[string]interface{}{"enable_wkd_lookup": false})
+
+	writeConfig(map[string]interface{}{"enable_wkd_lookup": true})
+	readConfig(map[string]interface{}{"enable_wkd_lookup": true})
+
+	// Fields not provided are left untouched
+	writeConfig(map[string]interface{}{})
+	readConfig(map[string]interface{}{"enable_wkd_lookup": true})
+
+	writeConfig(map[string]interface{}{"enable_wkd_lookup": false})
+	readConfig(map[string]interface{}{"enable_wkd_lookup": false})
+
+	readConfig(map[string]interface{}{"max_plaintext_bytes": 0})
+	writeConfig(map[string]interface{}{"max_plaintext_bytes": 1024})
+	readConfig(map[string]interface{}{"max_plaintext_bytes": 1024, "enable_wkd_lookup": false})
+
+	readConfig(map[string]interface{}{"require_mdc": true})
+	writeConfig(map[string]interface{}{"require_mdc": false})
+	readConfig(map[string]interface{}{"require_mdc": false, "max_plaintext_bytes": 1024})
+	writeConfig(map[string]interface{}{"require_mdc": true})
+	readConfig(map[string]interface{}{"require_mdc": true})
+
+	readConfig(map[string]interface{}{"recipient_key_min_bits": 0})
+	writeConfig(map[string]interface{}{"recipient_key_min_bits": 2048})
+	readConfig(map[string]interface{}{"recipient_key_min_bits": 2048, "max_plaintext_bytes": 1024})
+
+	readConfig(map[string]interface{}{"key_name_pattern": ""})
+	writeConfig(map[string]interface{}{"key_name_pattern": "^team-"})
+	readConfig(map[string]interface{}{"key_name_pattern": "^team-", "max_plaintext_bytes": 1024})
+
+	readConfig(map[string]interface{}{"max_key_count": 0})
+	writeConfig(map[string]interface{}{"max_key_count": 10})
+	readConfig(map[string]interface{}{"max_key_count": 10, "max_plaintext_bytes": 1024})
+
+	readConfig(map[string]interface{}{"debug_mode": false})
+	writeConfig(map[string]interface{}{"debug_mode": true})
+	readConfig(map[string]interface{}{"debug_mode": true, "require_mdc": true})
+
+	readConfig(map[string]interface{}{"allow_expired_key_decryption": false})
+	writeConfig(map[string]interface{}{"allow_expired_key_decryption": true})
+	readConfig(map[string]interface{}{"allow_expired_key_decryption": true, "require_mdc": true})
+
+	readConfig(map[string]interface{}{"allow_legacy_key_types": false})
+	writeConfig(map[string]interface{}{"allow_legacy_key_types": true})
+	readConfig(map[string]interface{}{"allow_legacy_key_types": true, "recipient_key_min_bits": 2048})
+
+	// Deleting the configuration resets all the settings to their defaults
+	writeConfig(map[string]interface{}{"enable_wkd_lookup": true})
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config/allowed-algorithms",
+		Data:      map[string]interface{}{"algorithms": []string{"sha2-256"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.DeleteOperation,
+		Path:      "config",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readConfig(map[string]interface{}{
+		"allow_expired_key_decryption": false,
+		"allow_legacy_key_types":       false,
+		"debug_mode":                   false,
+		"enable_wkd_lookup":            false,
+		"key_name_pattern":             "",
+		"max_key_count":                0,
+		"max_plaintext_bytes":          0,
+		"recipient_key_min_bits":       0,
+		"require_mdc":                  true,
+		"wkd_domain":                   "",
+	})
+	config, err := b.config(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.AllowedAlgorithms) != 0 {
+		t.Fatalf("expected no allowed algorithms, got: %#v", config.AllowedAlgorithms)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"max_plaintext_bytes": -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error response: %#v", resp)
+	}
+}
+
+func TestBackend_PathConfig_RoundTrip(t *testing.T) {
+	b, storage := getTestBackend(t)
+
+	request := func(operation logical.Operation, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      "config",
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		return resp
+	}
+	assertConfig := func(expected map[string]interface{}) {
+		if data := request(logical.ReadOperation, nil).Data; !reflect.DeepEqual(data, expected) {
+			t.Fatalf("expected config %#v, got: %#v", expected, data)
+		}
+	}
+
+	// Values differing from the defaults, written as an HTTP client would
+	written := map[string]interface{}{
+		"allow_expired_key_decryption": "true",
+		"allow_legacy_key_types":       "true",
+		"debug_mode":                   true,
+		"enable_wkd_lookup":            "true",
+		"key_name_pattern":             "^team-[a-z]+$",
+		"max_key_count":                "42",
+		"max_plaintext_bytes":          json.Number("1048576"),
+		"recipient_key_min_bits":       3072,
+		"require_mdc":                  false,
+		"wkd_domain":                   "Example.com",
+	}
+	expected := map[string]interface{}{
+		"allow_expired_key_decryption": true,
+		"allow_legacy_key_types":       true,
+		"debug_mode":                   true,
+		"enable_wkd_lookup":            true,
+		"key_name_pattern":             "^team-[a-z]+$",
+		"max_key_count":                42,
+		"max_plaintext_bytes":          1048576,
+		"recipient_key_min_bits":       3072,
+		"require_mdc":                  false,
+		"wkd_domain":                   "example.com",
+	}
+	for field := range pathConfig(b.(*backend)).Fields {
+		if _, ok := written[field]; !ok {
+			t.Fatalf("the config field %s is not covered", field)
+		}
+	}
+	request(logical.UpdateOperation, written)
+	assertConfig(expected)
+
+	// Writing a single field leaves the others untouched
+	for field, value := range map[string]interface{}{
+		"allow_expired_key_decryption": false,
+		"allow_legacy_key_types":       false,
+		"debug_mode":                   false,
+		"enable_wkd_lookup":            false,
+		"key_name_pattern":             "",
+		"max_key_count":                0,
+		"max_plaintext_bytes":          0,
+		"recipient_key_min_bits":       0,
+		"require_mdc":                  true,
+		"wkd_domain":                   "",
+	} {
+		request(logical.UpdateOperation, map[string]interface{}{field: value})
+		expected[field] = value
+		assertConfig(expected)
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 	"io"
 	"strings"
+	"time"
 )
 
 func pathDecrypt(b *backend) *framework.Path {
@@ -18,21 +20,25 @@ func pathDecrypt(b *backend) *framework.Path {
 		Pattern: "decrypt/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
+				Type:         framework.TypeString,
+				Description:  "The key to use",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"ciphertext": {
-				Type:        framework.TypeString,
-				Description: "The ciphertext to decrypt",
+				Type:         framework.TypeString,
+				Description:  "The ciphertext to decrypt",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Ciphertext", Group: "Input"},
 			},
 			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format the ciphertext uses. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Type:         framework.TypeString,
+				Default:      "base64",
+				Description:  `Encoding format the ciphertext uses. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Format", Group: "Output"},
 			},
 			"signer_key": {
-				Type:        framework.TypeString,
-				Description: "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Signer key", Group: "Key Settings"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -54,11 +60,13 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
 	}
 
-	keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	name := data.Get("name").(string)
+	keyEntry, err := b.key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if keyEntry == nil {
+		b.logOperationFailure(req, "decryption", name, "key_not_found")
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
 
@@ -67,14 +75,26 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 	if err != nil {
 		return nil, err
 	}
+	if keyring[0].PrivateKey == nil {
+		b.logOperationFailure(req, "decryption", name, "public_key_only")
+		return logical.ErrorResponse("key is public-key-only; cannot perform private key operations"), logical.ErrInvalidRequest
+	}
+	backendConfig, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if keyExpired(keyring[0], time.Now()) && !backendConfig.AllowExpiredKeyDecryption {
+		b.logOperationFailure(req, "decryption", name, "key_expired")
+		return logical.ErrorResponse(errKeyExpired), logical.ErrInvalidRequest
+	}
 
 	signerKey := data.Get("signer_key").(string)
 	if signerKey != "" {
-		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(signerKey))
+		signer, err := readArmoredKey(signerKey)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
-		keyring = append(keyring, el[0])
+		keyring = append(keyring, signer)
 	}
 
 	ciphertextEncoded := strings.NewReader(data.Get("ciphertext").(string))
@@ -85,19 +105,36 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 	case "ascii-armor":
 		block, err := armor.Decode(ciphertextEncoded)
 		if err != nil {
+			b.logOperationFailure(req, "decryption", name, "invalid_encoding")
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 		ciphertextDecoder = block.Body
 	}
 
-	md, err := openpgp.ReadMessage(ciphertextDecoder, keyring, nil, nil)
+	ciphertext, err := io.ReadAll(ciphertextDecoder)
+	if err != nil {
+		b.logOperationFailure(req, "decryption", name, "invalid_encoding")
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if !backendConfig.AllowMissingMDC {
+		// Unparsable ciphertexts are reported by the decryption below
+		if mdc, err := hasMDC(ciphertext); err == nil && !mdc {
+			b.logOperationFailure(req, "decryption", name, "missing_mdc")
+			return logical.ErrorResponse("message lacks MDC protection; possible tampering"), logical.ErrInvalidRequest
+		}
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(ciphertext), keyring, nil, nil)
 	if err != nil {
+		b.logOperationFailure(req, "decryption", name, "decryption_failed")
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	var plaintext bytes.Buffer
 	w := base64.NewEncoder(base64.StdEncoding, &plaintext)
 	if _, err = io.Copy(w, md.UnverifiedBody); err != nil {
+		b.logOperationFailure(req, "decryption", name, "decryption_failed")
 		return nil, err
 	}
 	if err = w.Close(); err != nil {
@@ -105,14 +142,35 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 	}
 
 	if signerKey != "" && (!md.IsSigned || md.SignedBy == nil || md.SignatureError != nil) {
+		b.logOperationFailure(req, "decryption", name, "invalid_signature")
 		return logical.ErrorResponse("Signature is invalid or not present"), nil
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"plaintext": plaintext.String(),
 		},
-	}, nil
+	}
+	if md.Signature != nil && md.SignatureError == nil {
+		resp.Warnings = signatureHashWarnings(md.Signature.Hash)
+	}
+	b.logKeyEvent(req, keyEventAccessed, name)
+	return resp, nil
+}
+
+// hasMDC reports whether the encrypted data of the message is protected by a
+// Modification Detection Code.
+func hasMDC(message []byte) (bool, error) {
+	packets := packet.NewReader(bytes.NewReader(message))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return false, err
+		}
+		if se, ok := p.(*packet.SymmetricallyEncrypted); ok {
+			return se.MDC, nil
+		}
+	}
 }
 
 const pathDecryptHelpSyn = "Decrypt a ciphertext value using a named GPG key"
@@ -120,4 +178,5 @@ const pathDecryptHelpSyn = "Decrypt a ciphertext value using a named GPG key"
 const pathDecryptHelpDesc = `
 This path uses the named GPG key from the request path to decrypt a user
 provided ciphertext. The plaintext is returned base64 encoded.
+It requires the "update" capability.
 `
@@ -14,6 +14,7 @@ import (
 	_ "golang.org/x/crypto/ripemd160"
 	"io"
 	"strings"
+	"time"
 )
 
 func pathEncrypt(b *backend) *framework.Path {
@@ -21,16 +22,19 @@ func pathEncrypt(b *backend) *framework.Path {
 		Pattern: "encrypt/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("urlalgorithm"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
+				Type:         framework.TypeString,
+				Description:  "The key to use",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"plaintext": {
-				Type:        framework.TypeString,
-				Description: "The plaintext to encrypt",
+				Type:         framework.TypeString,
+				Description:  "The plaintext to encrypt",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Plaintext", Sensitive: true, Group: "Input"},
 			},
 			"urlalgorithm": {
-				Type:        framework.TypeString,
-				Description: "Hash algorithm to use (POST URL parameter)",
+				Type:         framework.TypeString,
+				Description:  "Hash algorithm to use (POST URL parameter)",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Hash algorithm", Group: "Output"},
 			},
 			"algorithm": {
 				Type:    framework.TypeString,
@@ -43,15 +47,51 @@ func pathEncrypt(b *backend) *framework.Path {
 * sha2-512
 
 Defaults to "sha2-256".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Hash algorithm", Group: "Output"},
 			},
 			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Type:         framework.TypeString,
+				Default:      "base64",
+				Description:  `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Format", Group: "Output"},
 			},
 			"recipient_key": {
-				Type:        framework.TypeString,
-				Description: "The ASCII-armored GPG key of the recipient of the ciphertext.",
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored GPG key of the recipient of the ciphertext.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Recipient key", Sensitive: true, Group: "Key Settings"},
+			},
+			"recipient_email": {
+				Type:         framework.TypeString,
+				Description:  "The email of the recipient used to discover its GPG key with the Web Key Directory when recipient_key is not provided. The discovery must be enabled in the backend configuration.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Recipient email", Group: "Key Settings"},
+			},
+			"encrypt_to_subkey_only": {
+				Type:         framework.TypeBool,
+				Description:  "Refuses to encrypt to the primary key of the recipient when it has no usable encryption subkey.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Encrypt to subkey only", Group: "Key Settings"},
+			},
+			"recipient_key_required_flags": {
+				Type:    framework.TypeCommaStringSlice,
+				Default: []string{"encrypt"},
+				Description: `Capabilities the recipient key must have with a non-expired primary key or subkey. Valid values are:
+
+* encrypt
+* sign
+* certify
+* authenticate
+
+Defaults to "encrypt", which is always required.`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Recipient key required flags", Group: "Key Settings"},
+			},
+			"recipient_key_min_bits": {
+				Type:         framework.TypeInt,
+				Description:  "Minimum bit length of the primary key of the recipient. Defaults to the recipient_key_min_bits of the backend configuration.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Recipient key minimum bits", Group: "Key Settings"},
+			},
+			"time_override": {
+				Type:         framework.TypeString,
+				Description:  "RFC 3339 time used instead of the current time in the ciphertext. Only accepted when debug_mode is enabled in the backend configuration.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Time override", Group: "Output"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -65,10 +105,19 @@ Defaults to "sha2-256".`,
 }
 
 func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	backendConfig, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	plaintextB64 := data.Get("plaintext").(string)
-	plaintext, err := base64.StdEncoding.DecodeString(plaintextB64)
+	// The size is checked before decoding to not allocate oversized plaintexts
+	if backendConfig.MaxPlaintextBytes > 0 && base64DecodedLen(plaintextB64) > backendConfig.MaxPlaintextBytes {
+		return logical.ErrorResponse("plaintext exceeds maximum size"), logical.ErrInvalidRequest
+	}
+	plaintext, err := decodeBase64("plaintext", plaintextB64)
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode plaintext as base64: %s", err)), logical.ErrInvalidRequest
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	config := packet.Config{}
@@ -89,6 +138,16 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, da
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
+	if !algorithmAllowed(backendConfig, algorithm) {
+		return logical.ErrorResponse("algorithm not permitted by policy"), logical.ErrInvalidRequest
+	}
+	// The time override only applies to the packets, keys are checked against
+	// the current time
+	now := time.Now()
+	config.Time, err = timeOverride(backendConfig, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 
 	format := data.Get("format").(string)
 	switch format {
@@ -98,18 +157,61 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
 	}
 
+	for _, flag := range data.Get("recipient_key_required_flags").([]string) {
+		if _, ok := keyUsageFlags[flag]; !ok {
+			return logical.ErrorResponse(fmt.Sprintf("unsupported recipient key flag %s", flag)), nil
+		}
+	}
+
+	minBits := backendConfig.RecipientKeyMinBits
+	if v, ok := data.GetOk("recipient_key_min_bits"); ok {
+		minBits = v.(int)
+	}
+	if minBits < 0 {
+		return logical.ErrorResponse("recipient_key_min_bits must be positive"), logical.ErrInvalidRequest
+	}
+
+	var el openpgp.EntityList
 	recipientKey := data.Get("recipient_key").(string)
-	if recipientKey == "" {
+	recipientEmail := data.Get("recipient_email").(string)
+	switch {
+	case strings.TrimSpace(recipientKey) != "":
+		entity, err := readArmoredKey(recipientKey)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		el = openpgp.EntityList{entity}
+	case recipientEmail != "":
+		if !backendConfig.EnableWKDLookup {
+			return logical.ErrorResponse("WKD lookup is not enabled, recipient_key is required"), logical.ErrInvalidRequest
+		}
+		entity, err := b.wkdLookup(ctx, recipientEmail)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		el = openpgp.EntityList{entity}
+	default:
 		return logical.ErrorResponse("recipient_key not exist"), logical.ErrInvalidRequest
 	}
-	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(recipientKey))
-	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	if minBits > 0 {
+		bits, err := el[0].PrimaryKey.BitLength()
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if int(bits) < minBits {
+			return logical.ErrorResponse(fmt.Sprintf("recipient key is %d bits, at least %d bits are required", bits, minBits)), logical.ErrInvalidRequest
+		}
 	}
-	recipientKeyList := []*openpgp.Entity{el[0]}
-	if err != nil {
-		return nil, err
+	subkeyOnly := data.Get("encrypt_to_subkey_only").(bool)
+	if !hasEncryptionKey(el[0], now, !subkeyOnly) {
+		return logical.ErrorResponse("recipient key has no usable encryption subkey"), logical.ErrInvalidRequest
 	}
+	for _, flag := range data.Get("recipient_key_required_flags").([]string) {
+		if !hasKeyFlag(el[0], now, keyUsageFlags[flag]) {
+			return logical.ErrorResponse(fmt.Sprintf("recipient key lacks required capability: %s", flag)), logical.ErrInvalidRequest
+		}
+	}
+	recipientKeyList := []*openpgp.Entity{el[0]}
 
 	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
 	if err != nil {
@@ -118,10 +220,25 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, da
 	if entry == nil {
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
+	if !recipientKeyAlgorithmAllowed(entry, el[0].PrimaryKey.PubKeyAlgo) {
+		return logical.ErrorResponse("recipient_key_algorithm_not_allowed: the algorithm of the recipient key is not allowed for this key"), logical.ErrInvalidRequest
+	}
 	entity, err := b.entity(entry)
 	if err != nil {
 		return nil, err
 	}
+	if keyExpired(entity, now) {
+		return logical.ErrorResponse(errKeyExpired), logical.ErrInvalidRequest
+	}
+	// Public-key-only keys cannot sign, the message is left unsigned
+	signer := entity
+	if entity.PrivateKey == nil {
+		signer = nil
+	}
+	// The encryption cannot be interrupted once started
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	ciphertext := new(bytes.Buffer)
 	var ciphertextEncoder io.WriteCloser
@@ -136,7 +253,7 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, da
 		ciphertextEncoder = base64.NewEncoder(base64.StdEncoding, ciphertext)
 	}
 
-	w, err := openpgp.Encrypt(ciphertextEncoder, recipientKeyList, entity, nil, &config)
+	w, err := openpgp.Encrypt(ciphertextEncoder, recipientKeyList, signer, nil, &config)
 	if err != nil {
 		return nil, err
 	}
@@ -153,15 +270,104 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, da
 		return nil, err
 	}
 
-	return &logical.Response{
+	keyID, err := firstPacketKeyID(ciphertext.Bytes(), format)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"ciphertext": ciphertext.String(),
+			"key_id":     fmt.Sprintf("%016x", keyID),
 		},
-	}, nil
+	}
+	if signer != nil {
+		resp.Data["signer_key_id"] = fmt.Sprintf("%016x", signer.PrimaryKey.KeyId)
+		b.logKeyEvent(req, keyEventAccessed, data.Get("name").(string))
+	}
+	addKeyIdentifiers(resp.Data, publicKeyByID(el[0], keyID))
+	resp.Warnings = append(keyWarnings(el[0], now), keyWarnings(entity, now)...)
+	return resp, nil
+}
+
+// base64DecodedLen returns the length of the data encoded in a padded base64
+// string, without decoding it.
+func base64DecodedLen(s string) int {
+	n := base64.StdEncoding.DecodedLen(len(s))
+	if strings.HasSuffix(s, "==") {
+		return n - 2
+	}
+	if strings.HasSuffix(s, "=") {
+		return n - 1
+	}
+	return n
+}
+
+// hasEncryptionKey reports whether the entity holds a non-expired and non-revoked
+// key that can be used to encrypt a message. Like openpgp.Encrypt, the primary key
+// is only considered when no subkey is usable and allowPrimary is set.
+func hasEncryptionKey(e *openpgp.Entity, now time.Time, allowPrimary bool) bool {
+	if len(e.Revocations) > 0 {
+		return false
+	}
+	for _, subkey := range e.Subkeys {
+		if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation {
+			continue
+		}
+		if !subkey.Sig.FlagsValid || !subkey.Sig.FlagEncryptCommunications || subkey.Sig.KeyExpired(now) {
+			continue
+		}
+		switch subkey.PublicKey.PubKeyAlgo {
+		case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoElGamal, packet.PubKeyAlgoECDH:
+			return true
+		}
+	}
+
+	if !allowPrimary || !e.PrimaryKey.PubKeyAlgo.CanEncrypt() {
+		return false
+	}
+	ident := primaryIdentity(e)
+	if ident == nil {
+		return false
+	}
+	sig := ident.SelfSignature
+	return (!sig.FlagsValid || sig.FlagEncryptCommunications) && !sig.KeyExpired(now)
+}
+
+var keyUsageFlags = map[string]byte{
+	"encrypt":      keyFlagEncryptCommunicate | keyFlagEncryptStorage,
+	"sign":         keyFlagSign,
+	"certify":      keyFlagCertify,
+	"authenticate": keyFlagAuthenticate,
+}
+
+// hasKeyFlag reports whether the entity holds a non-expired and non-revoked
+// primary key or subkey having one of the key flags. Like hasEncryptionKey, a
+// key without key flags is considered usable for any purpose.
+func hasKeyFlag(e *openpgp.Entity, now time.Time, flag byte) bool {
+	if len(e.Revocations) > 0 {
+		return false
+	}
+	// The flags of the primary key are held by the primary identity only
+	if ident := primaryIdentity(e); ident != nil && !ident.SelfSignature.KeyExpired(now) {
+		if flags, ok := signatureKeyFlags(ident.SelfSignature); !ok || flags&flag != 0 {
+			return true
+		}
+	}
+	for _, subkey := range e.Subkeys {
+		if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation || subkey.Sig.KeyExpired(now) {
+			continue
+		}
+		if flags, ok := signatureKeyFlags(subkey.Sig); !ok || flags&flag != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 const pathEncryptHelpSyn = "Encrypt a plaintext value using the named GPG key"
 const pathEncryptHelpDesc = `
 This path uses the named GPG key from the request path to encrypt a user
 provided plaintext. The ciphertext is returned base64 encoded.
+It requires the "update" capability.
 `
@@ -0,0 +1,90 @@
+//go:build compat
+// +build compat
+
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// TestPathEncryptWrite_Base64URLInsteadOfBase64_Legacy documents the breaking
+// change of encoding base64 ciphertexts with the URL-safe alphabet instead of
+// the standard one: clients decoding them with the standard alphabet would
+// fail on the "-" and "_" characters. Until the plugin offers a distinct
+// format for it, clients migrating to URL-safe ciphertexts must re-encode them
+// with base64.URLEncoding and decode them back with base64.StdEncoding before
+// calling the decrypt endpoint. It is run with:
+//
+//	go test -tags compat -run Legacy ./gpg
+func TestPathEncryptWrite_Base64URLInsteadOfBase64_Legacy(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A ciphertext holds a character of the standard alphabet missing from the
+	// URL-safe one within a few attempts
+	var ciphertext string
+	for i := 0; i < 100 && !strings.ContainsAny(ciphertext, "+/"); i++ {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"format":        "base64",
+			"recipient_key": gpgPublicKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		ciphertext = resp.Data["ciphertext"].(string)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatalf("the ciphertext is not encoded with the standard alphabet: %s", err)
+	}
+	urlCiphertext := base64.URLEncoding.EncodeToString(raw)
+	if _, err := base64.StdEncoding.DecodeString(urlCiphertext); err == nil {
+		t.Fatal("expected legacy clients to fail decoding URL-safe ciphertexts")
+	}
+
+	// The migration path: URL-safe ciphertexts are decoded back before being
+	// decrypted
+	migrated, err := base64.URLEncoding.DecodeString(urlCiphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "decrypt/test"
+	req.Data = map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString(migrated),
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["plaintext"] != "QWxwYWNhcwo=" {
+		t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %v", resp.Data["plaintext"])
+	}
+}
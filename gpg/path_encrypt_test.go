@@ -0,0 +1,2208 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the tests")
+
+func TestGPG_EncryptRecipientKeyCapabilities(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(recipientKey string, subkeyOnly, errExpected bool) {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":              "QWxwYWNhcwo=",
+				"recipient_key":          recipientKey,
+				"encrypt_to_subkey_only": subkeyOnly,
+			},
+		})
+		if errExpected {
+			if !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if _, ok := resp.Data["ciphertext"]; !ok {
+			t.Fatalf("no ciphertext found in response data %#v", resp.Data)
+		}
+	}
+
+	withSubkey := testRecipientKey(t, true)
+	primaryOnly := testRecipientKey(t, false)
+
+	encrypt(withSubkey, false, false)
+	encrypt(withSubkey, true, false)
+	encrypt(primaryOnly, false, false)
+	encrypt(primaryOnly, true, true)
+
+	// Signing only key with an expired encryption subkey
+	encrypt(publicSignerKey, false, true)
+}
+
+func TestGPG_EncryptRecipientKeyWithoutEncryptionSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The primary key of the recipients certifies and signs only
+	recipientKey := func(subkeys ...func(*openpgp.Entity) openpgp.Subkey) string {
+		entity, err := openpgp.NewEntity("Vault GPG recipient", "", "recipient@example.com", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entity.Subkeys = nil
+		for _, subkey := range subkeys {
+			entity.Subkeys = append(entity.Subkeys, subkey(entity))
+		}
+		return testArmoredPrivateKey(t, entity)
+	}
+	expired := func(e *openpgp.Entity) openpgp.Subkey {
+		subkey := testSubkey(t, e, time.Now().Add(-48*time.Hour), packet.SigTypeSubkeyBinding)
+		lifetime := uint32((24 * time.Hour).Seconds())
+		subkey.Sig.KeyLifetimeSecs = &lifetime
+		return subkey
+	}
+	revoked := func(e *openpgp.Entity) openpgp.Subkey {
+		return testSubkey(t, e, time.Now(), packet.SigTypeSubkeyRevocation)
+	}
+
+	// ECDSA primary key without key flags, which cannot encrypt
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := packet.NewECDSAPrivateKey(time.Now(), ecdsaKey)
+	uid := packet.NewUserId("Vault GPG recipient", "", "recipient@example.com")
+	withoutFlags := &openpgp.Entity{
+		PrimaryKey: &primary.PublicKey,
+		PrivateKey: primary,
+		Identities: map[string]*openpgp.Identity{
+			uid.Id: {
+				Name:   uid.Id,
+				UserId: uid,
+				SelfSignature: &packet.Signature{
+					CreationTime: time.Now(),
+					SigType:      packet.SigTypePositiveCert,
+					PubKeyAlgo:   packet.PubKeyAlgoECDSA,
+					Hash:         crypto.SHA256,
+					IssuerKeyId:  &primary.KeyId,
+				},
+			},
+		},
+	}
+
+	for name, key := range map[string]string{
+		"signing only":              recipientKey(),
+		"ECDSA without key flags":   testArmoredPrivateKey(t, withoutFlags),
+		"expired encryption subkey": recipientKey(expired),
+		"revoked encryption subkey": recipientKey(revoked),
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"recipient_key": key,
+			},
+		})
+		if err != logical.ErrInvalidRequest || !resp.IsError() || resp.Error().Error() != "recipient key has no usable encryption subkey" {
+			t.Fatalf("%s: expected error response, got: %#v, %v", name, resp, err)
+		}
+	}
+}
+
+func TestGPG_EncryptRecipientKeyRequiredFlags(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The primary key certifies and signs, the subkey encrypts
+	recipientKey := testRecipientKey(t, true)
+
+	encrypt := func(flags []string, expectedError string) {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":                    "QWxwYWNhcwo=",
+				"recipient_key":                recipientKey,
+				"recipient_key_required_flags": flags,
+			},
+		})
+		if expectedError != "" {
+			if !resp.IsError() || resp.Error().Error() != expectedError {
+				t.Fatalf("%v: expected error response %q: %#v", flags, expectedError, resp)
+			}
+			return
+		}
+		if resp.IsError() {
+			t.Fatalf("%v: not expected error response: %#v", flags, *resp)
+		}
+	}
+
+	encrypt(nil, "")
+	encrypt([]string{"encrypt"}, "")
+	encrypt([]string{"encrypt", "sign", "certify"}, "")
+	encrypt([]string{"encrypt", "authenticate"}, "recipient key lacks required capability: authenticate")
+	encrypt([]string{"unknown"}, "unsupported recipient key flag unknown")
+
+	// Only the primary identity holds the flags of the primary key
+	entity, err := openpgp.NewEntity("Vault GPG recipient", "", "recipient@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ident := range entity.Identities {
+		ident.SelfSignature.FlagCertify = false
+	}
+	uid := packet.NewUserId("Vault GPG other", "", "other@example.com")
+	entity.Identities[uid.Id] = &openpgp.Identity{
+		Name:   uid.Id,
+		UserId: uid,
+		SelfSignature: &packet.Signature{
+			CreationTime: time.Now(),
+			SigType:      packet.SigTypePositiveCert,
+			PubKeyAlgo:   packet.PubKeyAlgoRSA,
+			Hash:         crypto.SHA256,
+			FlagsValid:   true,
+			FlagCertify:  true,
+			IssuerKeyId:  &entity.PrimaryKey.KeyId,
+		},
+	}
+	recipientKey = testArmoredPrivateKey(t, entity)
+	encrypt([]string{"sign"}, "")
+	encrypt([]string{"certify"}, "recipient key lacks required capability: certify")
+}
+
+func TestGPG_EncryptRecipientKeyMinBits(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	small, err := openpgp.NewEntity("Small", "", "small@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(path string, data map[string]interface{}) *logical.Response {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		return resp
+	}
+	request("keys/test", map[string]interface{}{"real_name": "Vault GPG test"})
+	request("keys/small", map[string]interface{}{"generate": false, "key": testArmoredPrivateKey(t, small)})
+	smallKey := testPublicKey(t, b, storage, "small")
+	recipientKey := testPublicKey(t, b, storage, "test")
+
+	encrypt := func(recipientKey string, minBits interface{}, expectedError string) {
+		data := map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": recipientKey,
+		}
+		if minBits != nil {
+			data["recipient_key_min_bits"] = minBits
+		}
+		resp := request("encrypt/test", data)
+		if expectedError != "" {
+			if !resp.IsError() || resp.Error().Error() != expectedError {
+				t.Fatalf("%v: expected error response %q: %#v", minBits, expectedError, resp)
+			}
+			return
+		}
+		if resp.IsError() {
+			t.Fatalf("%v: not expected error response: %#v", minBits, *resp)
+		}
+	}
+
+	encrypt(smallKey, nil, "")
+	encrypt(smallKey, 2048, "recipient key is 1024 bits, at least 2048 bits are required")
+	encrypt(recipientKey, 2048, "")
+	encrypt(recipientKey, 4096, "recipient key is 2048 bits, at least 4096 bits are required")
+	encrypt(recipientKey, -1, "recipient_key_min_bits must be positive")
+
+	// The minimum is inherited from the backend configuration
+	request("config", map[string]interface{}{"recipient_key_min_bits": 2048})
+	encrypt(smallKey, nil, "recipient key is 1024 bits, at least 2048 bits are required")
+	encrypt(recipientKey, nil, "")
+	encrypt(smallKey, 0, "")
+}
+
+func TestGPG_EncryptSHA224(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"algorithm":     "sha2-224",
+		"recipient_key": publicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	req.Path = "decrypt/test"
+	req.Data = map[string]interface{}{
+		"ciphertext": resp.Data["ciphertext"],
+		"signer_key": publicKey,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["plaintext"] != "QWxwYWNhcwo=" {
+		t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %s", resp.Data["plaintext"])
+	}
+}
+
+func TestGPG_EncryptEmptyPlaintext(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "",
+		"recipient_key": publicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["ciphertext"] == "" {
+		t.Fatal("expected a non empty ciphertext")
+	}
+
+	req.Path = "decrypt/test"
+	req.Data = map[string]interface{}{
+		"ciphertext": resp.Data["ciphertext"],
+		"signer_key": publicKey,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["plaintext"] != "" {
+		t.Fatalf("expected empty plaintext, got: %s", resp.Data["plaintext"])
+	}
+}
+
+func TestGPG_EncryptMaxSizePlaintext(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	const maxPlaintextBytes = 1 << 20
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"max_plaintext_bytes": maxPlaintextBytes,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Path = "keys/test"
+	req.Data = map[string]interface{}{
+		"real_name": "Vault GPG test",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	encrypt := func(size int) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     base64.StdEncoding.EncodeToString(make([]byte, size)),
+				"recipient_key": publicKey,
+			},
+		})
+	}
+
+	resp, err := encrypt(maxPlaintextBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	for _, plaintext := range []string{
+		base64.StdEncoding.EncodeToString(make([]byte, maxPlaintextBytes+1)),
+		// The plaintext must be rejected without being decoded, which would
+		// report the invalid encoding instead
+		strings.Repeat("!", base64.StdEncoding.EncodedLen(maxPlaintextBytes+1)),
+	} {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     plaintext,
+				"recipient_key": publicKey,
+			},
+		})
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("expected invalid request error, got: %v", err)
+		}
+		if !resp.IsError() || resp.Error().Error() != "plaintext exceeds maximum size" {
+			t.Fatalf("expected plaintext size error response: %#v", resp)
+		}
+	}
+}
+
+// TestBackend_ConcurrentEncryptOperations is meant to be run repeatedly
+// with the race detector:
+//
+//	go test -race -count=100 -run TestBackend_ConcurrentEncryptOperations ./gpg
+func TestBackend_ConcurrentEncryptOperations(t *testing.T) {
+	t.Parallel()
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 100
+	ciphertexts := make([]string, n)
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < n; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			// All the goroutines encrypt at once to maximize the contention
+			start.Wait()
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "encrypt/test",
+				Data: map[string]interface{}{
+					"plaintext":     "QWxwYWNhcwo=",
+					"recipient_key": gpgPublicKey,
+				},
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.IsError() {
+				t.Errorf("not expected error response: %#v", *resp)
+				return
+			}
+			ciphertexts[i] = resp.Data["ciphertext"].(string)
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for i, ciphertext := range ciphertexts {
+		message, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		plaintext, err := ioutil.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if string(plaintext) != "Alpacas\n" {
+			t.Fatalf("%d: expected plaintext %q, got: %q", i, "Alpacas\n", plaintext)
+		}
+	}
+}
+
+func TestGPG_EncryptURLAlgorithm(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	encrypt := func(path, bodyAlgorithm string, errExpected bool) {
+		data := map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": publicKey,
+		}
+		if bodyAlgorithm != "" {
+			data["algorithm"] = bodyAlgorithm
+		}
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if errExpected {
+			if err == nil && !resp.IsError() {
+				t.Fatalf("%s: expected error response: %#v", path, resp)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", path, *resp)
+		}
+	}
+
+	encrypt("encrypt/test/sha2-512", "", false)
+	encrypt("encrypt/test/md5", "", true)
+	encrypt("encrypt/test/md5", "sha2-512", true)
+	// The algorithm of the URL takes precedence over the one of the body
+	encrypt("encrypt/test/sha2-512", "md5", false)
+	encrypt("encrypt/test", "sha2-384", false)
+}
+
+func TestGPG_EncryptKeyID(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	for _, format := range []string{"base64", "ascii-armor"} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"format":        format,
+			"recipient_key": publicKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		// ID of the encryption subkey
+		if resp.Data["key_id"] != "4fcca897d922fd7d" {
+			t.Fatalf("%s: expected key_id 4fcca897d922fd7d, got: %s", format, resp.Data["key_id"])
+		}
+		if resp.Data["key_id_long"] != "4fcca897d922fd7d" || resp.Data["key_id_short"] != "d922fd7d" {
+			t.Fatalf("%s: unexpected key IDs: %s, %s", format, resp.Data["key_id_long"], resp.Data["key_id_short"])
+		}
+		if fingerprint := resp.Data["fingerprint_v4"].(string); len(fingerprint) != 40 || fingerprint[24:] != "4fcca897d922fd7d" {
+			t.Fatalf("%s: unexpected fingerprint_v4: %s", format, fingerprint)
+		}
+	}
+}
+
+func TestGPG_EncryptAllAlgorithmFormatCombinations(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	cases := []struct {
+		algorithm   string
+		format      string
+		expectError bool
+	}{
+		{"sha2-224", "base64", false},
+		{"sha2-224", "ascii-armor", false},
+		{"sha2-256", "base64", false},
+		{"sha2-256", "ascii-armor", false},
+		{"sha2-384", "base64", false},
+		{"sha2-384", "ascii-armor", false},
+		{"sha2-512", "base64", false},
+		{"sha2-512", "ascii-armor", false},
+		{"sha1", "base64", true},
+		{"md5", "ascii-armor", true},
+		{"SHA2-256", "base64", true},
+		{"", "base64", true},
+		{"sha2-256", "hex", true},
+		{"sha2-256", "ASCII-ARMOR", true},
+		{"sha2-256", "", true},
+	}
+
+	for _, c := range cases {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"algorithm":     c.algorithm,
+				"format":        c.format,
+				"recipient_key": publicKey,
+			},
+		})
+		if c.expectError {
+			if !resp.IsError() {
+				t.Fatalf("%s/%s: expected error response: %#v", c.algorithm, c.format, resp)
+			}
+			continue
+		}
+		if resp.IsError() {
+			t.Fatalf("%s/%s: not expected error response: %#v", c.algorithm, c.format, *resp)
+		}
+
+		ciphertext := resp.Data["ciphertext"].(string)
+		switch c.format {
+		case "base64":
+			if _, err := base64.StdEncoding.DecodeString(ciphertext); err != nil {
+				t.Fatalf("%s/%s: ciphertext is not base64 encoded: %s", c.algorithm, c.format, err)
+			}
+		case "ascii-armor":
+			if !strings.HasPrefix(ciphertext, "-----BEGIN PGP MESSAGE-----") {
+				t.Fatalf("%s/%s: ciphertext is not ASCII-armored: %s", c.algorithm, c.format, ciphertext)
+			}
+		}
+	}
+}
+
+// TestGPG_EncryptGolden compares the deterministic parts of the encrypted messages
+// with the golden files of the testdata directory, the time of the signatures
+// being fixed with time_override. Run the tests with -update to regenerate them
+// after an intended format change.
+func TestGPG_EncryptGolden(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "config"
+	req.Data = map[string]interface{}{"debug_mode": true}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, algorithm := range supportedAlgorithms {
+		for _, format := range []string{"base64", "ascii-armor"} {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "encrypt/test",
+				Data: map[string]interface{}{
+					"plaintext":     "QWxwYWNhcwo=",
+					"algorithm":     algorithm,
+					"format":        format,
+					"recipient_key": publicKey,
+					"time_override": "2020-01-02T03:04:05Z",
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsError() {
+				t.Fatalf("not expected error response: %#v", *resp)
+			}
+
+			summary := testEncryptedMessageSummary(t, resp.Data["ciphertext"].(string), format, keyring)
+			golden := filepath.Join("testdata", "encrypt_"+algorithm+"_"+format+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(summary), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary != string(expected) {
+				t.Errorf("%s/%s: output does not match %s:\n%s", algorithm, format, golden, summary)
+			}
+		}
+	}
+}
+
+// testEncryptedMessageSummary decodes an encrypted message and describes the parts
+// of it that do not depend on randomness.
+func testEncryptedMessageSummary(t *testing.T, ciphertext, format string, keyring openpgp.EntityList) string {
+	var sb strings.Builder
+	var body io.Reader
+	switch format {
+	case "ascii-armor":
+		block, err := armor.Decode(strings.NewReader(ciphertext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&sb, "armor_type: %s\n", block.Type)
+		var headers []string
+		for k, v := range block.Header {
+			headers = append(headers, k+": "+v)
+		}
+		sort.Strings(headers)
+		for _, header := range headers {
+			fmt.Fprintf(&sb, "armor_header: %s\n", header)
+		}
+		body = block.Body
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, strings.NewReader(ciphertext))
+	}
+	message, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := testEncryptedMessagePackets(t, message, keyring)
+	encryptedKey, ok := packets.outer[0].(*packet.EncryptedKey)
+	if !ok {
+		t.Fatalf("expected a public key encrypted session key packet, got %T", packets.outer[0])
+	}
+	fmt.Fprintf(&sb, "encrypted_key_id: %016X\n", encryptedKey.KeyId)
+	fmt.Fprintf(&sb, "encrypted_key_algorithm: %d\n", encryptedKey.Algo)
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(md.UnverifiedBody); err != nil {
+		t.Fatal(err)
+	}
+	if md.SignatureError != nil {
+		t.Fatal(md.SignatureError)
+	}
+	fmt.Fprintf(&sb, "signed_by_key_id: %016X\n", md.SignedByKeyId)
+	fmt.Fprintf(&sb, "signature_time: %d\n", md.Signature.CreationTime.Unix())
+	fmt.Fprintf(&sb, "literal_is_binary: %t\n", md.LiteralData.IsBinary)
+	fmt.Fprintf(&sb, "literal_file_name: %q\n", md.LiteralData.FileName)
+	fmt.Fprintf(&sb, "literal_time: %d\n", md.LiteralData.Time)
+	return sb.String()
+}
+
+// testEncryptedPackets holds the packets of an encrypted message, up to its
+// encrypted data packet, and the packets of the decrypted data, the compressed
+// packets being replaced by their contents.
+type testEncryptedPackets struct {
+	outer     []packet.Packet
+	inner     []packet.Packet
+	plaintext []byte
+}
+
+// testEncryptedMessagePackets reads the packets of an encrypted message after
+// decrypting its session key with the keyring.
+func testEncryptedMessagePackets(t *testing.T, message []byte, keyring openpgp.EntityList) testEncryptedPackets {
+	var result testEncryptedPackets
+	var encryptedKeys []*packet.EncryptedKey
+	var encryptedData *packet.SymmetricallyEncrypted
+	packets := packet.NewReader(bytes.NewReader(message))
+	for encryptedData == nil {
+		p, err := packets.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.outer = append(result.outer, p)
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			encryptedKeys = append(encryptedKeys, p)
+		case *packet.SymmetricallyEncrypted:
+			encryptedData = p
+		}
+	}
+
+	var sessionKey *packet.EncryptedKey
+	for _, encryptedKey := range encryptedKeys {
+		for _, key := range keyring.KeysById(encryptedKey.KeyId) {
+			if key.PrivateKey != nil && encryptedKey.Decrypt(key.PrivateKey, nil) == nil {
+				sessionKey = encryptedKey
+			}
+		}
+	}
+	if sessionKey == nil {
+		t.Fatal("no session key can be decrypted with the keyring")
+	}
+	contents, err := encryptedData.Decrypt(sessionKey.CipherFunc, sessionKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets = packet.NewReader(contents)
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch p := p.(type) {
+		case *packet.Compressed:
+			packets.Push(p.Body)
+			continue
+		case *packet.LiteralData:
+			if result.plaintext, err = ioutil.ReadAll(p.Body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result.inner = append(result.inner, p)
+	}
+	return result
+}
+
+// testPacketTypes returns the types of the packets.
+func testPacketTypes(packets []packet.Packet) []string {
+	types := make([]string, len(packets))
+	for i, p := range packets {
+		types[i] = fmt.Sprintf("%T", p)
+	}
+	return types
+}
+
+// algorithmFormat is a combination of the algorithm and format parameters of
+// the encrypt path, mixing valid values with random strings.
+type algorithmFormat struct {
+	Algorithm string
+	Format    string
+}
+
+func (algorithmFormat) Generate(r *rand.Rand, size int) reflect.Value {
+	pick := func(valid []string) string {
+		if r.Intn(2) == 0 {
+			return valid[r.Intn(len(valid))]
+		}
+		v, _ := quick.Value(reflect.TypeOf(""), r)
+		return v.String()
+	}
+	return reflect.ValueOf(algorithmFormat{
+		Algorithm: pick(supportedAlgorithms),
+		Format:    pick([]string{"base64", "ascii-armor"}),
+	})
+}
+
+func (c algorithmFormat) valid() bool {
+	validAlgorithm := false
+	for _, algorithm := range supportedAlgorithms {
+		if c.Algorithm == algorithm {
+			validAlgorithm = true
+		}
+	}
+	return validAlgorithm && (c.Format == "base64" || c.Format == "ascii-armor")
+}
+
+func TestGPG_EncryptAlgorithmFormatCombinations(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	property := func(c algorithmFormat) bool {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"algorithm":     c.Algorithm,
+				"format":        c.Format,
+				"recipient_key": publicKey,
+			},
+		})
+		if c.valid() {
+			return err == nil && resp != nil && !resp.IsError() && resp.Data["ciphertext"] != ""
+		}
+		// Invalid parameters must be reported as a client error, never as an
+		// internal one.
+		if err != nil && err != logical.ErrInvalidRequest {
+			return false
+		}
+		return resp.IsError() && resp.Error().Error() != ""
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testPublicKey returns the ASCII-armored public key of a named key.
+func TestGPG_EncryptErrors(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := testPublicKey(t, b, storage, "test")
+
+	// The entry is valid JSON but the key cannot be parsed
+	entry, err := logical.StorageEntryJSON("key/corrupted", &keyEntry{
+		Version:       keyEntryVersion,
+		SerializedKey: []byte("not a key"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name          string
+		path          string
+		data          map[string]interface{}
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:          "invalid base64 plaintext",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo", "recipient_key": publicKey},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "unable to decode plaintext as base64: illegal base64 data at input byte 8",
+		},
+		{
+			name:          "time override without debug mode",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey, "time_override": "2020-01-01T00:00:00Z"},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "time_override requires debug_mode to be enabled in the backend configuration",
+		},
+		{
+			name:          "missing recipient key",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo="},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "recipient_key not exist",
+		},
+		{
+			name:          "invalid recipient key",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": "not armored"},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "openpgp: invalid argument: no armored data found",
+		},
+		{
+			name:          "key not found",
+			path:          "encrypt/doNotExist",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "key not found",
+		},
+		{
+			name:          "unsupported algorithm",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey, "algorithm": "sha1"},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "unsupported algorithm sha1",
+		},
+		{
+			name:          "unsupported format",
+			path:          "encrypt/test",
+			data:          map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey, "format": "hex"},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `unsupported encoding format hex; must be "base64" or "ascii-armor"`,
+		},
+		{
+			name:         "corrupted key",
+			path:         "encrypt/corrupted",
+			data:         map[string]interface{}{"plaintext": "QWxwYWNhcwo=", "recipient_key": publicKey},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range cases {
+		req.Path = c.path
+		req.Data = c.data
+		resp, err := b.HandleRequest(context.Background(), req)
+		code, _ := logical.RespondErrorCommon(req, resp, err)
+		if code != c.expectedCode {
+			t.Fatalf("%s: expected status code %d, got: %d", c.name, c.expectedCode, code)
+		}
+		if c.expectedError == "" {
+			continue
+		}
+		if !resp.IsError() || resp.Error().Error() != c.expectedError {
+			t.Fatalf("%s: expected error response %q, got: %#v", c.name, c.expectedError, resp)
+		}
+	}
+}
+
+func TestPathEncryptWrite_RecipientKeyNotInStorage(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	// The entry is valid JSON but the key cannot be parsed
+	entry, err := logical.StorageEntryJSON("key/corrupted", &keyEntry{
+		Version:       keyEntryVersion,
+		SerializedKey: []byte("not a key"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	_, parseErr := openpgp.ReadKeyRing(bytes.NewReader([]byte("not a key")))
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/corrupted",
+		Data: map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": testRecipientKey(t, true),
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	code, _ := logical.RespondErrorCommon(req, resp, err)
+	if code != http.StatusInternalServerError {
+		t.Fatalf("expected status code %d, got: %d", http.StatusInternalServerError, code)
+	}
+	if err != errCorruptedKey {
+		t.Fatalf("expected error %q, got: %v", errCorruptedKey, err)
+	}
+	if strings.Contains(err.Error(), parseErr.Error()) {
+		t.Fatalf("the parsing error is disclosed: %s", err)
+	}
+}
+
+// TestPathEncryptWrite_ASCIIArmorOutput checks that the ascii-armor format returns
+// the armored message itself, not base64 encoded, holding a valid packet sequence.
+func TestPathEncryptWrite_ASCIIArmorOutput(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"format":        "ascii-armor",
+		"recipient_key": gpgPublicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	ciphertext := resp.Data["ciphertext"].(string)
+	if _, err := base64.StdEncoding.DecodeString(ciphertext); err == nil {
+		t.Fatal("the armored ciphertext must not be base64 encoded")
+	}
+	if !strings.HasPrefix(ciphertext, "-----BEGIN PGP MESSAGE-----\n") {
+		t.Fatalf("expected the armor header, got: %s", ciphertext)
+	}
+	if !strings.HasSuffix(ciphertext, "-----END PGP MESSAGE-----") {
+		t.Fatalf("expected the armor footer, got: %s", ciphertext)
+	}
+
+	block, err := armor.Decode(strings.NewReader(ciphertext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Type != "PGP MESSAGE" {
+		t.Fatalf("expected a PGP MESSAGE armor block, got %s", block.Type)
+	}
+	message, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := testEncryptedMessagePackets(t, message, keyring)
+	expected := []string{"*packet.EncryptedKey", "*packet.SymmetricallyEncrypted"}
+	if types := testPacketTypes(packets.outer); !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected packets %v, got: %v", expected, types)
+	}
+	if string(packets.plaintext) != "Alpacas\n" {
+		t.Fatalf("expected plaintext Alpacas, got: %q", packets.plaintext)
+	}
+}
+
+// TestPathEncryptWrite_Base64Output checks that the base64 format returns a
+// complete message, which would be truncated if the encoder were closed before
+// the encrypted data.
+func TestPathEncryptWrite_Base64Output(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plaintexts of several lengths exercise the padding of the base64 output
+	for _, plaintext := range []string{"", "A", "Al", "Alpacas\n", strings.Repeat("Alpacas\n", 1000)} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     base64.StdEncoding.EncodeToString([]byte(plaintext)),
+			"format":        "base64",
+			"recipient_key": gpgPublicKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+
+		message, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+		if err != nil {
+			t.Fatalf("invalid base64 ciphertext: %s", err)
+		}
+		md, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := ioutil.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The signature and the MDC are checked once the whole message is read
+		if md.SignatureError != nil {
+			t.Fatal(md.SignatureError)
+		}
+		if string(decrypted) != plaintext {
+			t.Fatalf("expected plaintext of %d bytes, got %d bytes", len(plaintext), len(decrypted))
+		}
+	}
+}
+
+// TestPathEncryptWrite_SignerEntityUsed checks that the messages are signed by
+// the named key, which is passed as the signer to openpgp.Encrypt.
+func TestPathEncryptWrite_SignerEntityUsed(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/signer",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG signer",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "encrypt/signer"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": gpgPublicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerKeyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "signer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := signerKeyring[0]
+	keyring = append(keyring, signer)
+
+	message, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(md.UnverifiedBody); err != nil {
+		t.Fatal(err)
+	}
+	if !md.IsSigned {
+		t.Fatal("expected a signed message")
+	}
+	if md.SignedByKeyId != signer.PrimaryKey.KeyId {
+		t.Fatalf("expected the message to be signed by %016X, got %016X", signer.PrimaryKey.KeyId, md.SignedByKeyId)
+	}
+	if md.SignedBy == nil || md.SignedBy.PublicKey.Fingerprint != signer.PrimaryKey.Fingerprint {
+		t.Fatal("the signer key is not the stored key")
+	}
+	if md.SignatureError != nil {
+		t.Fatalf("invalid signature: %s", md.SignatureError)
+	}
+}
+
+func testPublicKey(t *testing.T, b logical.Backend, storage logical.Storage, name string) string {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/" + name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() {
+		t.Fatalf("not expected response: %#v", resp)
+	}
+	return resp.Data["public_key"].(string)
+}
+
+// testRecipientKey generates an ASCII-armored key whose primary key can be used
+// for encryption, with or without an encryption subkey.
+func testRecipientKey(t *testing.T, withSubkey bool) string {
+	entity, err := openpgp.NewEntity("Vault GPG recipient", "", "recipient@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ident := range entity.Identities {
+		ident.SelfSignature.FlagEncryptCommunications = true
+	}
+	if !withSubkey {
+		entity.Subkeys = nil
+	}
+
+	return testArmoredPrivateKey(t, entity)
+}
+
+func TestPathEncryptWrite_DecryptedByExternalGPG(t *testing.T) {
+	gpgPath, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg binary not found in the PATH")
+	}
+	home := t.TempDir()
+	runGPG := func(stdin io.Reader, args ...string) []byte {
+		cmd := exec.Command(gpgPath, append([]string{"--homedir", home, "--batch", "--no-tty"}, args...)...)
+		cmd.Stdin = stdin
+		out, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				t.Fatalf("gpg %s: %s\n%s", strings.Join(args, " "), err, exitErr.Stderr)
+			}
+			t.Fatal(err)
+		}
+		return out
+	}
+	defer exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+	request := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", path, *resp)
+		}
+		return resp
+	}
+	request("keys/test", map[string]interface{}{"real_name": "Vault GPG test"})
+	runGPG(strings.NewReader(gpgKey), "--import")
+	runGPG(strings.NewReader(testPublicKey(t, b, storage, "test")), "--import")
+
+	resp := request("encrypt/test", map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": gpgPublicKey,
+	})
+	ciphertext, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := filepath.Join(home, "message.gpg")
+	if err := ioutil.WriteFile(message, ciphertext, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext := runGPG(nil, "--trust-model", "always", "--decrypt", message); string(plaintext) != "Alpacas\n" {
+		t.Fatalf("expected plaintext %q, got: %q", "Alpacas\n", plaintext)
+	}
+
+	resp = request("sign/test", map[string]interface{}{"input": "QWxwYWNhcwo="})
+	signature, err := base64.StdEncoding.DecodeString(resp.Data["signature"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureFile := filepath.Join(home, "message.sig")
+	if err := ioutil.WriteFile(signatureFile, signature, 0600); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(home, "message")
+	if err := ioutil.WriteFile(input, []byte("Alpacas\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runGPG(nil, "--verify", signatureFile, input)
+}
+
+func TestPathEncryptWrite_ProducedCiphertextParseable(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": gpgPublicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("the packet scanner panicked: %v", r)
+		}
+	}()
+
+	packets := testEncryptedMessagePackets(t, ciphertext, keyring)
+	var encryptedKey *packet.EncryptedKey
+	var encryptedData *packet.SymmetricallyEncrypted
+	for _, p := range packets.outer {
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			if encryptedKey != nil {
+				t.Fatal("expected a single public-key encrypted session key packet")
+			}
+			encryptedKey = p
+		case *packet.SymmetricallyEncrypted:
+			encryptedData = p
+		default:
+			t.Fatalf("unexpected %T packet", p)
+		}
+	}
+	if encryptedKey == nil {
+		t.Fatal("no public-key encrypted session key packet found")
+	}
+	keys := keyring.KeysById(encryptedKey.KeyId)
+	if len(keys) != 1 || keys[0].PublicKey.KeyId != keyring[0].Subkeys[0].PublicKey.KeyId {
+		t.Fatalf("the session key is not encrypted to the recipient encryption key, got key ID %016X", encryptedKey.KeyId)
+	}
+	if !encryptedData.MDC {
+		t.Fatal("expected a symmetrically encrypted integrity protected data packet")
+	}
+	if string(packets.plaintext) != "Alpacas\n" {
+		t.Fatalf("expected plaintext %q, got: %q", "Alpacas\n", packets.plaintext)
+	}
+}
+
+func TestPathEncryptWrite_ASCIIArmorDecodesToValidPackets(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The packet library never signs encrypted messages with SHA-224, it
+	// falls back to SHA-256
+	for _, c := range []struct {
+		algorithm string
+		hash      crypto.Hash
+	}{
+		{"sha2-224", crypto.SHA256},
+		{"sha2-256", crypto.SHA256},
+		{"sha2-384", crypto.SHA384},
+		{"sha2-512", crypto.SHA512},
+	} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"format":        "ascii-armor",
+			"algorithm":     c.algorithm,
+			"recipient_key": gpgPublicKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", c.algorithm, *resp)
+		}
+		block, err := armor.Decode(strings.NewReader(resp.Data["ciphertext"].(string)))
+		if err != nil {
+			t.Fatalf("%s: %s", c.algorithm, err)
+		}
+		message, err := ioutil.ReadAll(block.Body)
+		if err != nil {
+			t.Fatalf("%s: %s", c.algorithm, err)
+		}
+
+		packets := testEncryptedMessagePackets(t, message, keyring)
+		expected := []string{"*packet.EncryptedKey", "*packet.SymmetricallyEncrypted"}
+		if types := testPacketTypes(packets.outer); !reflect.DeepEqual(types, expected) {
+			t.Fatalf("%s: expected packets %v, got: %v", c.algorithm, expected, types)
+		}
+		if !packets.outer[1].(*packet.SymmetricallyEncrypted).MDC {
+			t.Fatalf("%s: expected integrity protected data", c.algorithm)
+		}
+		// The signed message may be compressed
+		expected = []string{"*packet.OnePassSignature", "*packet.LiteralData", "*packet.Signature"}
+		if types := testPacketTypes(packets.inner); !reflect.DeepEqual(types, expected) {
+			t.Fatalf("%s: expected encrypted packets %v, got: %v", c.algorithm, expected, types)
+		}
+		if hash := packets.inner[0].(*packet.OnePassSignature).Hash; hash != c.hash {
+			t.Fatalf("%s: expected the %s hash, got %s", c.algorithm, c.hash, hash)
+		}
+		if hash := packets.inner[2].(*packet.Signature).Hash; hash != c.hash {
+			t.Fatalf("%s: expected the %s hash, got %s", c.algorithm, c.hash, hash)
+		}
+		if string(packets.plaintext) != "Alpacas\n" {
+			t.Fatalf("%s: expected plaintext %q, got: %q", c.algorithm, "Alpacas\n", packets.plaintext)
+		}
+	}
+}
+
+func TestPathEncryptWrite_UnsupportedAlgorithmReturns400(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty algorithm provided in the payload does not fall back to the
+	// default one
+	for _, algorithm := range []string{"sha3-999", "md5", ""} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"algorithm":     algorithm,
+			"recipient_key": gpgPublicKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%q: not expected error: %s", algorithm, err)
+		}
+		if resp == nil || !resp.IsError() || resp.Error().Error() != "unsupported algorithm "+algorithm {
+			t.Fatalf("%q: expected unsupported algorithm error response, got: %#v", algorithm, resp)
+		}
+		if code, _ := logical.RespondErrorCommon(req, resp, err); code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status code %d, got: %d", algorithm, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestPathEncryptWrite_InvalidBase64PlaintextReturns400(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "!!!NOT_BASE64!!!",
+		"recipient_key": gpgPublicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected error %q, got: %v", logical.ErrInvalidRequest, err)
+	}
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "unable to decode plaintext as base64") {
+		t.Fatalf("expected base64 decoding error response, got: %#v", resp)
+	}
+	if code, _ := logical.RespondErrorCommon(req, resp, err); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %d, got: %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestPathEncryptWrite_EmptyRecipientKeyReturns400(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A recipient key holding only whitespace is handled as a missing one
+	for _, recipientKey := range []string{"", " \t\n"} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": recipientKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("%q: expected error %q, got: %v", recipientKey, logical.ErrInvalidRequest, err)
+		}
+		if !resp.IsError() || resp.Error().Error() != "recipient_key not exist" {
+			t.Fatalf("%q: expected missing recipient key error response, got: %#v", recipientKey, resp)
+		}
+		if code, _ := logical.RespondErrorCommon(req, resp, err); code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status code %d, got: %d", recipientKey, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestPathEncryptWrite_MalformedRecipientKeyReturns400(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		recipientKey  string
+		expectedError string
+	}{
+		// Without the blank line ending the armor headers, the block is not found
+		{"-----BEGIN PGP PUBLIC KEY BLOCK-----\nXXXXXXXX\n-----END PGP PUBLIC KEY BLOCK-----", "openpgp: invalid argument: no armored data found"},
+		// Neither base64 nor OpenPGP packets in the armored body
+		{"-----BEGIN PGP PUBLIC KEY BLOCK-----\n\n!!!!!!!!\n-----END PGP PUBLIC KEY BLOCK-----", ""},
+		{"-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nXXXXXXXX\n-----END PGP PUBLIC KEY BLOCK-----", ""},
+	} {
+		req.Path = "encrypt/test"
+		req.Data = map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": c.recipientKey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if code, _ := logical.RespondErrorCommon(req, resp, err); code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status code %d, got: %d", c.recipientKey, http.StatusBadRequest, code)
+		}
+		if !resp.IsError() {
+			t.Fatalf("%q: expected error response, got: %#v", c.recipientKey, resp)
+		}
+		if c.expectedError != "" && resp.Error().Error() != c.expectedError {
+			t.Fatalf("%q: expected error %q, got: %q", c.recipientKey, c.expectedError, resp.Error())
+		}
+	}
+}
+
+// keyReadErrorStorage fails to read the key entries.
+type keyReadErrorStorage struct {
+	logical.InmemStorage
+}
+
+var errKeyRead = errors.New("unable to read the key entry")
+
+func (s *keyReadErrorStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if strings.HasPrefix(key, "key/") {
+		return nil, errKeyRead
+	}
+	return s.InmemStorage.Get(ctx, key)
+}
+
+func TestPathEncryptWrite_StorageReadError(t *testing.T) {
+	storage := &keyReadErrorStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/test",
+		Data: map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"recipient_key": gpgPublicKey,
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if resp != nil {
+		t.Fatalf("not expected response: %#v", resp)
+	}
+	if !errors.Is(err, errKeyRead) {
+		t.Fatalf("expected error %q, got: %v", errKeyRead, err)
+	}
+	if code, _ := logical.RespondErrorCommon(req, resp, err); code != http.StatusInternalServerError {
+		t.Fatalf("expected status code %d, got: %d", http.StatusInternalServerError, code)
+	}
+}
+
+func TestPathEncryptWrite_EntityParseError(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	entry, err := logical.StorageEntryJSON("key/corrupted", &keyEntry{
+		Version:       keyEntryVersion,
+		SerializedKey: []byte{0xc5, 0x01, 0x00},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrupted key is a server error, unlike a missing one
+	for _, c := range []struct {
+		name         string
+		expectedCode int
+	}{
+		{"corrupted", http.StatusInternalServerError},
+		{"doNotExist", http.StatusBadRequest},
+	} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/" + c.name,
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"recipient_key": gpgPublicKey,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if code, _ := logical.RespondErrorCommon(req, resp, err); code != c.expectedCode {
+			t.Fatalf("%s: expected status code %d, got: %d", c.name, c.expectedCode, code)
+		}
+		switch c.expectedCode {
+		case http.StatusInternalServerError:
+			if resp != nil || err != errCorruptedKey {
+				t.Fatalf("%s: expected error %q, got: %#v, %v", c.name, errCorruptedKey, resp, err)
+			}
+		case http.StatusBadRequest:
+			if err != logical.ErrInvalidRequest || !resp.IsError() || resp.Error().Error() != "key not found" {
+				t.Fatalf("%s: expected key not found error response, got: %#v, %v", c.name, resp, err)
+			}
+		}
+	}
+}
+
+func TestPathEncryptWrite_LargeRecipientKeyRing(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A long-lived key with 50 identities certified twice by the key itself
+	// and 20 subkeys, the additional ones having no capability the backend
+	// uses, like authentication subkeys
+	entity, err := openpgp.NewEntity("Vault GPG recipient", "", "recipient@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 1; i < 50; i++ {
+		uid := packet.NewUserId("Vault GPG recipient", fmt.Sprintf("identity %d", i), "recipient@example.com")
+		ident := &openpgp.Identity{
+			Name:   uid.Id,
+			UserId: uid,
+			SelfSignature: &packet.Signature{
+				SigType:      packet.SigTypePositiveCert,
+				PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+				Hash:         crypto.SHA256,
+				CreationTime: now,
+				IssuerKeyId:  &entity.PrimaryKey.KeyId,
+				FlagsValid:   true,
+				FlagSign:     true,
+				FlagCertify:  true,
+			},
+		}
+		sig := &packet.Signature{
+			SigType:      packet.SigTypeGenericCert,
+			PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+			Hash:         crypto.SHA256,
+			CreationTime: now.Add(-time.Minute),
+			IssuerKeyId:  &entity.PrimaryKey.KeyId,
+		}
+		if err := sig.SignUserId(uid.Id, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+			t.Fatal(err)
+		}
+		ident.Signatures = append(ident.Signatures, sig)
+		entity.Identities[uid.Id] = ident
+	}
+	for i := 1; i < 20; i++ {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subkey := packet.NewECDSAPrivateKey(now, priv)
+		subkey.IsSubkey = true
+		subkey.PublicKey.IsSubkey = true
+		entity.Subkeys = append(entity.Subkeys, openpgp.Subkey{
+			PublicKey:  &subkey.PublicKey,
+			PrivateKey: subkey,
+			Sig: &packet.Signature{
+				SigType:      packet.SigTypeSubkeyBinding,
+				PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+				Hash:         crypto.SHA256,
+				CreationTime: now,
+				IssuerKeyId:  &entity.PrimaryKey.KeyId,
+				FlagsValid:   true,
+			},
+		})
+	}
+	recipientKey := testArmoredPrivateKey(t, entity)
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(recipientKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el[0].Identities) != 50 || len(el[0].Subkeys) != 20 {
+		t.Fatalf("expected 50 identities and 20 subkeys, got %d and %d", len(el[0].Identities), len(el[0].Subkeys))
+	}
+
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": recipientKey,
+	}
+	start := time.Now()
+	resp, err := b.HandleRequest(context.Background(), req)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the encryption to complete within 500ms, took %s", elapsed)
+	}
+	t.Logf("encrypted to a key with 50 identities and 20 subkeys in %s", elapsed)
+	if resp.Data["key_id"] != fmt.Sprintf("%016x", entity.Subkeys[0].PublicKey.KeyId) {
+		t.Fatalf("expected the ciphertext to be encrypted to the encryption subkey, got: %v", resp.Data["key_id"])
+	}
+}
+
+// keyReadHookStorage calls a hook when reading the key entries.
+type keyReadHookStorage struct {
+	logical.InmemStorage
+	hook func(ctx context.Context) error
+}
+
+func (s *keyReadHookStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	entry, err := s.InmemStorage.Get(ctx, key)
+	if err == nil && strings.HasPrefix(key, "key/") {
+		err = s.hook(ctx)
+	}
+	return entry, err
+}
+
+func TestPathEncryptWrite_ContextCancellation(t *testing.T) {
+	storage := &keyReadHookStorage{hook: func(context.Context) error { return nil }}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(ctx context.Context) error {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"recipient_key": gpgPublicKey,
+			},
+		})
+		if resp != nil {
+			t.Fatalf("not expected response: %#v", resp)
+		}
+		return err
+	}
+
+	// Canceled before the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := encrypt(ctx); err != context.Canceled {
+		t.Fatalf("expected error %q, got: %v", context.Canceled, err)
+	}
+
+	// Canceled while the storage read blocks
+	ctx, cancel = context.WithCancel(context.Background())
+	storage.hook = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if err := encrypt(ctx); err != context.Canceled {
+		t.Fatalf("expected error %q, got: %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the request to return promptly, took %s", elapsed)
+	}
+
+	// Canceled once the key is read, before the encryption starts
+	ctx, cancel = context.WithCancel(context.Background())
+	storage.hook = func(context.Context) error {
+		cancel()
+		return nil
+	}
+	if err := encrypt(ctx); err != context.Canceled {
+		t.Fatalf("expected error %q, got: %v", context.Canceled, err)
+	}
+}
+
+func TestPathEncrypt_HelpText_NotEmpty(t *testing.T) {
+	if pathEncryptHelpSyn == "" || strings.Contains(pathEncryptHelpSyn, "\n") {
+		t.Fatalf("expected a single line synopsis, got: %q", pathEncryptHelpSyn)
+	}
+	if description := strings.TrimSpace(pathEncryptHelpDesc); !strings.Contains(description, "\n") {
+		t.Fatalf("expected a multi-line description, got: %q", pathEncryptHelpDesc)
+	}
+
+	path := pathEncrypt(Backend())
+	if path.HelpSynopsis != pathEncryptHelpSyn || path.HelpDescription != pathEncryptHelpDesc {
+		t.Fatal("the encrypt path does not use its help text")
+	}
+}
+
+func TestPathEncryptWrite_FormatBase64Default(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	if format := pathEncrypt(b).Fields["format"].Default; format != "base64" {
+		t.Fatalf("expected the base64 default format, got: %v", format)
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "encrypt/test"
+	req.Data = map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": gpgPublicKey,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	ciphertext := resp.Data["ciphertext"].(string)
+	if strings.HasPrefix(ciphertext, "-----BEGIN") {
+		t.Fatalf("expected a base64 ciphertext, got an armored one: %s", ciphertext)
+	}
+	message, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatalf("invalid base64 ciphertext: %s", err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPathEncryptWrite_AlgorithmURLOverridesBody(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		path     string
+		expected crypto.Hash
+	}{
+		{"encrypt/test/sha2-512", crypto.SHA512},
+		{"encrypt/test", crypto.SHA256},
+	} {
+		if hash := testEncryptSignatureHash(t, b, storage, c.path, "sha2-256"); hash != c.expected {
+			t.Fatalf("%s: expected the %s hash, got %s", c.path, c.expected, hash)
+		}
+	}
+}
+
+func TestPathEncryptWrite_AlgorithmBodyUsedWhenURLEmpty(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if hash := testEncryptSignatureHash(t, b, storage, "encrypt/test", "sha2-384"); hash != crypto.SHA384 {
+		t.Fatalf("expected the %s hash, got %s", crypto.SHA384, hash)
+	}
+}
+
+// testEncryptSignatureHash encrypts a plaintext to the test key with the
+// algorithm of the body and returns the hash of the signature of the message.
+func testEncryptSignatureHash(t *testing.T, b logical.Backend, storage logical.Storage, path, algorithm string) crypto.Hash {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      path,
+		Data: map[string]interface{}{
+			"plaintext":     "QWxwYWNhcwo=",
+			"algorithm":     algorithm,
+			"recipient_key": gpgPublicKey,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("%s: not expected error response: %#v", path, *resp)
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(message), keyring, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The signature is read once the literal data is consumed
+	if _, err := ioutil.ReadAll(md.UnverifiedBody); err != nil {
+		t.Fatal(err)
+	}
+	if md.Signature == nil {
+		t.Fatalf("%s: expected a signed message", path)
+	}
+	return md.Signature.Hash
+}
@@ -14,8 +14,9 @@ func pathExportKeys(b *backend) *framework.Path {
 		Pattern: "export/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "Name of the key",
+				Type:         framework.TypeString,
+				Description:  "Name of the key",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -28,6 +29,26 @@ func pathExportKeys(b *backend) *framework.Path {
 	}
 }
 
+func pathPublicKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/public-key",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathPublicKeyRead,
+			},
+		},
+		HelpSynopsis:    pathPublicKeyHelpSyn,
+		HelpDescription: pathPublicKeyHelpDesc,
+	}
+}
+
 func (b *backend) pathExportKeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 	entry, err := b.key(ctx, req.Storage, name)
@@ -40,6 +61,7 @@ func (b *backend) pathExportKeyRead(ctx context.Context, req *logical.Request, d
 	if !entry.Exportable {
 		return logical.ErrorResponse("key is not exportable"), nil
 	}
+	b.logKeyEvent(req, keyEventAccessed, name)
 
 	var buf bytes.Buffer
 	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
@@ -59,5 +81,40 @@ func (b *backend) pathExportKeyRead(ctx context.Context, req *logical.Request, d
 	}, nil
 }
 
+func (b *backend) pathPublicKeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := armoredPublicKey(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":       name,
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
 const pathExportHelpSyn = "Export named GPG key"
-const pathExportHelpDesc = "This path is used to export the keys that are configured as exportable."
+const pathExportHelpDesc = `
+This path is used to export the keys that are configured as exportable.
+It requires the "read" capability.
+`
+
+const pathPublicKeyHelpSyn = "Export the public key of a named GPG key"
+const pathPublicKeyHelpDesc = `
+This path is used to export the ASCII-armored public key of a named GPG key.
+The key does not need to be exportable. It requires the "read" capability.
+`
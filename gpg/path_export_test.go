@@ -2,8 +2,15 @@ package gpg
 
 import (
 	"context"
-	"github.com/hashicorp/vault/sdk/logical"
+	"encoding/hex"
+	"io"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 func TestGPG_ExportNotExistingKeyReturnsNotFound(t *testing.T) {
@@ -94,3 +101,183 @@ func TestGPG_ExportKey(t *testing.T) {
 		t.Fatalf("not expected name, expected test got: %s", name)
 	}
 }
+
+func TestGPG_ExportPublicKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqKey := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	respKey, err := b.HandleRequest(context.Background(), reqKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqExp := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test/public-key",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqExp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["name"] != "test" {
+		t.Fatalf("not expected name, expected test got: %s", resp.Data["name"])
+	}
+	if resp.Data["public_key"] != respKey.Data["public_key"] {
+		t.Fatalf("public key does not match: %s %s", resp.Data["public_key"], respKey.Data["public_key"])
+	}
+
+	reqExp.Path = "keys/doNotExist/public-key"
+	resp, err = b.HandleRequest(context.Background(), reqExp)
+	if !(resp == nil && err == nil) {
+		t.Fatal("Key does not exist but does not return not found")
+	}
+}
+
+func TestBackend_ExportPublicKey_IsValidOpenPGP(t *testing.T) {
+	b, storage := getTestBackend(t)
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", path, *resp)
+		}
+		return resp
+	}
+	request(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":  "Vault GPG test",
+		"email":      "vault@example.com",
+		"exportable": true,
+	})
+	fingerprint := request(logical.ReadOperation, "keys/test", nil).Data["fingerprint"]
+	publicKey := request(logical.ReadOperation, "keys/test/public-key", nil).Data["public_key"].(string)
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el) != 1 {
+		t.Fatalf("expected a single key, got %d", len(el))
+	}
+	entity := el[0]
+	if entity.PrivateKey != nil {
+		t.Fatal("the exported public key holds the private key")
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil {
+			t.Fatal("the exported public key holds the private key of a subkey")
+		}
+	}
+	if hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]) != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %x", fingerprint, entity.PrimaryKey.Fingerprint)
+	}
+	if _, ok := entity.Identities["Vault GPG test <vault@example.com>"]; !ok || len(entity.Identities) != 1 {
+		t.Fatalf("expected the identity of the created key, got: %#v", entity.Identities)
+	}
+
+	// No secret key packet is found in the armored block either
+	block, err := armor.Decode(strings.NewReader(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets := packet.NewReader(block.Body)
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p.(*packet.PrivateKey); ok {
+			t.Fatal("the exported public key holds a secret key packet")
+		}
+	}
+}
+
+func TestBackend_ImportExportRoundTrip_PreservesFingerprint(t *testing.T) {
+	b, storage := getTestBackend(t)
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", path, *resp)
+		}
+		return resp
+	}
+	request(logical.UpdateOperation, "config", map[string]interface{}{"allow_legacy_key_types": true})
+
+	for _, keyType := range []string{"rsa-2048", "rsa-4096", "ecdsa-p256", "dsa-elgamal"} {
+		key := testKeyFixture(t, keyType)
+		request(logical.UpdateOperation, "keys/"+keyType, map[string]interface{}{
+			"generate":   false,
+			"key":        key,
+			"exportable": true,
+		})
+
+		original, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{"keys/" + keyType + "/public-key", "export/" + keyType} {
+			data := request(logical.ReadOperation, path, nil).Data
+			armored, ok := data["public_key"].(string)
+			if !ok {
+				armored = data["key"].(string)
+			}
+			exported, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+			if err != nil {
+				t.Fatalf("%s: %s", path, err)
+			}
+			if original[0].PrimaryKey.Fingerprint != exported[0].PrimaryKey.Fingerprint {
+				t.Fatalf("%s: expected fingerprint %x, got %x", path, original[0].PrimaryKey.Fingerprint, exported[0].PrimaryKey.Fingerprint)
+			}
+			if len(original[0].Subkeys) != len(exported[0].Subkeys) {
+				t.Fatalf("%s: expected %d subkeys, got %d", path, len(original[0].Subkeys), len(exported[0].Subkeys))
+			}
+			for i, subkey := range original[0].Subkeys {
+				if subkey.PublicKey.Fingerprint != exported[0].Subkeys[i].PublicKey.Fingerprint {
+					t.Fatalf("%s: expected subkey fingerprint %x, got %x", path, subkey.PublicKey.Fingerprint, exported[0].Subkeys[i].PublicKey.Fingerprint)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gpg
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/HAECHI-LABS/vault-gpg-plugin/version"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const openpgpModulePath = "golang.org/x/crypto"
+
+var supportedAlgorithms = []string{
+	"sha2-224",
+	"sha2-256",
+	"sha2-384",
+	"sha2-512",
+}
+
+func pathInfo(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "info/?$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathInfoRead,
+			},
+		},
+		HelpSynopsis:    pathInfoHelpSyn,
+		HelpDescription: pathInfoHelpDesc,
+	}
+}
+
+func (b *backend) pathInfoRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"version":                 version.Version,
+			"git_commit":              version.GitCommit,
+			"build_date":              version.BuildDate,
+			"go_version":              runtime.Version(),
+			"openpgp_library_version": openpgpLibraryVersion(),
+			"supported_algorithms":    supportedAlgorithms,
+		},
+	}, nil
+}
+
+func openpgpLibraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != openpgpModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
+
+const pathInfoHelpSyn = "Return the build information of the plugin"
+const pathInfoHelpDesc = `
+This path returns the version and the build metadata of the plugin
+as well as the hash algorithms it supports.
+It requires the "read" capability.
+`
@@ -0,0 +1,40 @@
+package gpg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestGPG_Info(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "info",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() {
+		t.Fatalf("not expected response: %#v", resp)
+	}
+
+	for _, field := range []string{"version", "git_commit", "build_date", "go_version", "openpgp_library_version", "supported_algorithms"} {
+		if _, ok := resp.Data[field]; !ok {
+			t.Fatalf("no %s found in response data %#v", field, resp.Data)
+		}
+	}
+	if resp.Data["go_version"] == "" {
+		t.Fatal("go version is empty")
+	}
+	algorithms := resp.Data["supported_algorithms"].([]string)
+	if !reflect.DeepEqual(algorithms, supportedAlgorithms) {
+		t.Fatalf("expected algorithms %#v, got: %#v", supportedAlgorithms, algorithms)
+	}
+}
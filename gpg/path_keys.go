@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"io"
+	"math"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -29,43 +33,90 @@ func pathListKeys(b *backend) *framework.Path {
 	}
 }
 
+// reservedKeyName cannot be used as the name of a key as the search path would
+// shadow it.
+const reservedKeyName = "search"
+
+func pathSearchKeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + reservedKeyName + "/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"email": {
+				Type:         framework.TypeString,
+				Description:  "Email of an identity of the keys to find.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Email", Group: "Input"},
+			},
+			"name_substring": {
+				Type:         framework.TypeString,
+				Description:  "Substring of the real name of an identity of the keys to find.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Name substring", Group: "Input"},
+			},
+			"fingerprint_prefix": {
+				Type:         framework.TypeString,
+				Description:  "Prefix of the hex-encoded fingerprint of the keys to find.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Fingerprint prefix", Group: "Input"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeySearch,
+			},
+		},
+		HelpSynopsis:    pathSearchKeysHelpSyn,
+		HelpDescription: pathSearchKeysHelpDesc,
+	}
+}
+
 func pathKeys(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "Name of the key.",
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"real_name": {
-				Type:        framework.TypeString,
-				Description: "The real name of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Type:         framework.TypeString,
+				Description:  "The real name of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Real name", Group: "Key Settings"},
 			},
 			"email": {
-				Type:        framework.TypeString,
-				Description: "The email of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Type:         framework.TypeString,
+				Description:  "The email of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Email", Group: "Key Settings"},
 			},
 			"comment": {
-				Type:        framework.TypeString,
-				Description: "The comment of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Type:         framework.TypeString,
+				Description:  "The comment of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Comment", Group: "Key Settings"},
 			},
 			"key_bits": {
-				Type:        framework.TypeInt,
-				Default:     2048,
-				Description: "The number of bits to use. Only used if generate is true.",
+				Type:         framework.TypeInt,
+				Default:      2048,
+				Description:  "The number of bits to use. Only used if generate is true.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key bits", Group: "Key Settings"},
 			},
 			"key": {
-				Type:        framework.TypeString,
-				Description: "The ASCII-armored GPG key to use. Only used if generate is false.",
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored GPG key to use. Only used if generate is false.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Private key", Sensitive: true, Group: "Key Settings"},
+			},
+			"expiration": {
+				Type:         framework.TypeDurationSecond,
+				Description:  "Validity period of the generated GPG key, after which it expires. Defaults to 0, meaning the key never expires. Only used if generate is true.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Expiration", Group: "Key Settings"},
 			},
 			"exportable": {
-				Type:        framework.TypeBool,
-				Description: "Enables the key to be exportable.",
+				Type:         framework.TypeBool,
+				Description:  "Enables the key to be exportable.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Exportable", Group: "Key Settings"},
 			},
 			"generate": {
-				Type:        framework.TypeBool,
-				Default:     true,
-				Description: "Determines if a key should be generated by Vault or if a key is being passed from another service.",
+				Type:         framework.TypeBool,
+				Default:      true,
+				Description:  "Determines if a key should be generated by Vault or if a key is being passed from another service.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Generate", Group: "Key Settings"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -101,11 +152,16 @@ func (b *backend) key(ctx context.Context, s logical.Storage, name string) (*key
 	return &result, nil
 }
 
+// errCorruptedKey is returned instead of the parsing error of a stored key so
+// that no detail of the key material is disclosed to the caller.
+var errCorruptedKey = errors.New("unable to load the stored key")
+
 func (b *backend) entity(entry *keyEntry) (*openpgp.Entity, error) {
 	r := bytes.NewReader(entry.SerializedKey)
 	el, err := openpgp.ReadKeyRing(r)
 	if err != nil {
-		return nil, err
+		b.Logger().Error("unable to parse the stored key", "error", err)
+		return nil, errCorruptedKey
 	}
 
 	return el[0], nil
@@ -117,9 +173,11 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 	if e.PrivateKey != nil {
 		foundPrivateKey = true
 		err = e.PrivateKey.Serialize(w)
-		if err != nil {
-			return
-		}
+	} else {
+		err = e.PrimaryKey.Serialize(w)
+	}
+	if err != nil {
+		return
 	}
 	for _, ident := range e.Identities {
 		err = ident.UserId.Serialize(w)
@@ -135,9 +193,11 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 		if subkey.PrivateKey != nil {
 			foundPrivateKey = true
 			err = subkey.PrivateKey.Serialize(w)
-			if err != nil {
-				return
-			}
+		} else {
+			err = subkey.PublicKey.Serialize(w)
+		}
+		if err != nil {
+			return
 		}
 		err = subkey.Sig.Serialize(w)
 		if err != nil {
@@ -164,24 +224,83 @@ func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *f
 	if err != nil {
 		return nil, err
 	}
-
-	var buf bytes.Buffer
-	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	publicKey, err := armoredPublicKey(entity)
 	if err != nil {
 		return nil, err
 	}
-	err = entity.Serialize(w)
-	if err != nil || w.Close() != nil {
-		return nil, err
-	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
-			"public_key":  buf.String(),
+			"public_key":  publicKey,
 			"exportable":  entry.Exportable,
 		},
-	}, nil
+	}
+	addKeyIdentifiers(resp.Data, entity.PrimaryKey)
+	resp.Warnings = keyWarnings(entity, time.Now())
+	return resp, nil
+}
+
+const errKeyExpired = "key has expired"
+
+const errLegacyKeyTypesNotAllowed = "DSA and ElGamal keys are legacy key types, allow_legacy_key_types must be enabled in the backend configuration to import them"
+
+// hasLegacyKeyType reports whether the entity has a DSA primary key or a DSA or
+// ElGamal subkey, which can only be imported from legacy PGP keys.
+func hasLegacyKeyType(e *openpgp.Entity) bool {
+	if isLegacyKeyAlgorithm(e.PrimaryKey.PubKeyAlgo) {
+		return true
+	}
+	for _, subkey := range e.Subkeys {
+		if isLegacyKeyAlgorithm(subkey.PublicKey.PubKeyAlgo) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLegacyKeyAlgorithm(algorithm packet.PublicKeyAlgorithm) bool {
+	return algorithm == packet.PubKeyAlgoDSA || algorithm == packet.PubKeyAlgoElGamal
+}
+
+// addKeyIdentifiers adds the v4 fingerprint and the short and long key IDs of
+// the public key to the response data. They are empty when there is no key.
+func addKeyIdentifiers(data map[string]interface{}, pk *packet.PublicKey) {
+	if pk == nil {
+		data["fingerprint_v4"] = ""
+		data["key_id_long"] = ""
+		data["key_id_short"] = ""
+		return
+	}
+	fingerprint := hex.EncodeToString(pk.Fingerprint[:])
+	data["fingerprint_v4"] = fingerprint
+	data["key_id_long"] = fingerprint[len(fingerprint)-16:]
+	data["key_id_short"] = fingerprint[len(fingerprint)-8:]
+}
+
+// publicKeyByID returns the primary key or the subkey of the entity having the
+// key ID, or nil when there is none.
+func publicKeyByID(e *openpgp.Entity, keyID uint64) *packet.PublicKey {
+	keys := openpgp.EntityList{e}.KeysById(keyID)
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[0].PublicKey
+}
+
+func armoredPublicKey(entity *openpgp.Entity) (string, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err = entity.Serialize(w); err != nil {
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -190,10 +309,16 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 	email := data.Get("email").(string)
 	comment := data.Get("comment").(string)
 	keyBits := data.Get("key_bits").(int)
+	expiration := data.Get("expiration").(int)
 	exportable := data.Get("exportable").(bool)
 	generate := data.Get("generate").(bool)
 	key := data.Get("key").(string)
 
+	// The key would be shadowed by the search path
+	if name == reservedKeyName {
+		return logical.ErrorResponse(fmt.Sprintf("the key name %s is reserved", name)), nil
+	}
+
 	lock := locksutil.LockForKey(b.keyLocks, name)
 	lock.Lock()
 	defer lock.Unlock()
@@ -206,6 +331,32 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		return logical.ErrorResponse("key already exists"), nil
 	}
 
+	backendConfig, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if backendConfig.KeyNamePattern != "" {
+		pattern, err := regexp.Compile(backendConfig.KeyNamePattern)
+		if err != nil {
+			return nil, err
+		}
+		if !pattern.MatchString(name) {
+			return logical.ErrorResponse(fmt.Sprintf("the key name %s does not match the key_name_pattern %s", name, backendConfig.KeyNamePattern)), nil
+		}
+	}
+	if backendConfig.MaxKeyCount > 0 {
+		// Keys of different names are created concurrently otherwise
+		b.keyCountLock.Lock()
+		defer b.keyCountLock.Unlock()
+		keys, err := req.Storage.List(ctx, "key/")
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) >= backendConfig.MaxKeyCount {
+			return logical.ErrorResponse(fmt.Sprintf("the maximum number of keys (%d) has been reached", backendConfig.MaxKeyCount)), nil
+		}
+	}
+
 	var buf bytes.Buffer
 	switch generate {
 	case true:
@@ -215,10 +366,26 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		config := packet.Config{
 			RSABits: keyBits,
 		}
+		if expiration < 0 {
+			return logical.ErrorResponse("expiration must be positive"), nil
+		}
+		if expiration > math.MaxUint32 {
+			return logical.ErrorResponse("expiration is too large"), nil
+		}
 		entity, err := openpgp.NewEntity(realName, comment, email, &config)
 		if err != nil {
 			return nil, err
 		}
+		if expiration > 0 {
+			// The signatures holding the lifetimes are signed when serialized
+			lifetime := uint32(expiration)
+			for _, ident := range entity.Identities {
+				ident.SelfSignature.KeyLifetimeSecs = &lifetime
+			}
+			for _, subkey := range entity.Subkeys {
+				subkey.Sig.KeyLifetimeSecs = &lifetime
+			}
+		}
 		err = entity.SerializePrivate(&buf, nil)
 		if err != nil {
 			return nil, err
@@ -227,17 +394,21 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		if key == "" {
 			return logical.ErrorResponse("the key value is required for generated keys"), nil
 		}
-		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		entity, err := readArmoredKey(key)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), nil
 		}
-		err = serializePrivateWithoutSigning(&buf, el[0])
+		if hasLegacyKeyType(entity) && !backendConfig.AllowLegacyKeyTypes {
+			return logical.ErrorResponse(errLegacyKeyTypesNotAllowed), nil
+		}
+		err = serializePrivateWithoutSigning(&buf, entity)
 		if err != nil {
 			return logical.ErrorResponse("the key could not be serialized, is a private key present?"), nil
 		}
 	}
 
 	entry, err := logical.StorageEntryJSON("key/"+name, &keyEntry{
+		Version:       keyEntryVersion,
 		SerializedKey: buf.Bytes(),
 		Exportable:    exportable,
 	})
@@ -261,6 +432,7 @@ func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data
 	if err != nil {
 		return nil, err
 	}
+	b.logKeyEvent(req, keyEventDeleted, name)
 	return nil, nil
 }
 
@@ -273,14 +445,88 @@ func (b *backend) pathKeyList(
 	return logical.ListResponse(entries), nil
 }
 
+func (b *backend) pathKeySearch(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	email := strings.ToLower(data.Get("email").(string))
+	nameSubstring := strings.ToLower(data.Get("name_substring").(string))
+	fingerprintPrefix := strings.ToLower(data.Get("fingerprint_prefix").(string))
+	if email == "" && nameSubstring == "" && fingerprintPrefix == "" {
+		return logical.ErrorResponse("one of email, name_substring or fingerprint_prefix is required"), logical.ErrInvalidRequest
+	}
+
+	names, err := req.Storage.List(ctx, "key/")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, name := range names {
+		entry, err := b.key(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		entity, err := b.entity(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		fingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint[:])
+		if !strings.HasPrefix(fingerprint, fingerprintPrefix) {
+			continue
+		}
+		for _, ident := range entity.Identities {
+			if email != "" && strings.ToLower(ident.UserId.Email) != email {
+				continue
+			}
+			if !strings.Contains(strings.ToLower(ident.UserId.Name), nameSubstring) {
+				continue
+			}
+			keys = append(keys, name)
+			info := map[string]interface{}{
+				"fingerprint": fingerprint,
+			}
+			addKeyIdentifiers(info, entity.PrimaryKey)
+			keyInfo[name] = info
+			break
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// keyEntryVersion is the version of the storage format of the key entries
+// written by this version of the plugin. Entries written by older versions
+// of the plugin have no version.
+const keyEntryVersion = 1
+
 type keyEntry struct {
-	SerializedKey []byte
-	Exportable    bool
+	Version                       int
+	SerializedKey                 []byte
+	Exportable                    bool
+	AllowedRecipientKeyAlgorithms []string
+	// DisableSubkeyAutoSign is the negation of auto_sign_on_create so that the
+	// keys stored before its introduction sign their new subkeys.
+	DisableSubkeyAutoSign bool
 }
 
+const pathSearchKeysHelpSyn = "Search the named GPG keys"
+const pathSearchKeysHelpDesc = `
+This path is used to find the named GPG keys having an identity matching
+all the provided criteria. The comparisons are case-insensitive. As this
+path shadows it, no key can be named "search" and its creation is refused.
+It requires the "update" capability.
+`
+
 const pathPolicyHelpSyn = "Managed named GPG keys"
 const pathPolicyHelpDesc = `
 This path is used to manage the named GPG keys that are available.
 Doing a write with no value against a new named key will create
 it using a randomly generated key.
+
+Reading a key requires the "read" capability, creating a key the "update"
+capability, deleting a key the "delete" capability and listing the keys
+the "list" capability.
 `
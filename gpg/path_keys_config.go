@@ -0,0 +1,155 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// pubKeyAlgoEdDSA is the EdDSA public key algorithm identifier, it is not
+// defined by the packet library.
+const pubKeyAlgoEdDSA packet.PublicKeyAlgorithm = 22
+
+var recipientKeyAlgorithms = map[packet.PublicKeyAlgorithm]string{
+	packet.PubKeyAlgoRSA:            "rsa",
+	packet.PubKeyAlgoRSAEncryptOnly: "rsa",
+	packet.PubKeyAlgoRSASignOnly:    "rsa",
+	packet.PubKeyAlgoDSA:            "dsa",
+	packet.PubKeyAlgoElGamal:        "elgamal",
+	packet.PubKeyAlgoECDH:           "ecdh",
+	packet.PubKeyAlgoECDSA:          "ecdsa",
+	pubKeyAlgoEdDSA:                 "eddsa",
+}
+
+func pathKeysConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/config",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+			"allowed_recipient_key_algorithms": {
+				Type: framework.TypeStringSlice,
+				Description: `Algorithms the primary key of a recipient must use to encrypt with this key. Valid values are:
+
+* rsa
+* dsa
+* elgamal
+* ecdh
+* ecdsa
+* eddsa
+
+Defaults to all algorithms.`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Allowed recipient key algorithms", Group: "Key Settings"},
+			},
+			"auto_sign_on_create": {
+				Type:         framework.TypeBool,
+				Default:      true,
+				Description:  "Issues the binding signature of the subkeys added to the key with its primary key. When disabled, the added subkeys must hold a binding signature issued by the primary key. Defaults to true.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Auto sign on create", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathKeysConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysConfigWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysConfigHelpSyn,
+		HelpDescription: pathKeysConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed_recipient_key_algorithms": entry.AllowedRecipientKeyAlgorithms,
+			"auto_sign_on_create":              !entry.DisableSubkeyAutoSign,
+		},
+	}, nil
+}
+
+func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+
+	if raw, ok := data.GetOk("allowed_recipient_key_algorithms"); ok {
+		algorithms := strutil.RemoveDuplicates(raw.([]string), true)
+		for _, algorithm := range algorithms {
+			if !isRecipientKeyAlgorithm(algorithm) {
+				return logical.ErrorResponse(fmt.Sprintf("unsupported recipient key algorithm %s", algorithm)), nil
+			}
+		}
+		entry.AllowedRecipientKeyAlgorithms = algorithms
+	}
+	if autoSign, ok := data.GetOk("auto_sign_on_create"); ok {
+		entry.DisableSubkeyAutoSign = !autoSign.(bool)
+	}
+	entry.Version = keyEntryVersion
+
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func isRecipientKeyAlgorithm(algorithm string) bool {
+	for _, name := range recipientKeyAlgorithms {
+		if name == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+// recipientKeyAlgorithmAllowed reports whether the algorithm of the primary key
+// of a recipient is allowed by the configuration of the key entry.
+func recipientKeyAlgorithmAllowed(entry *keyEntry, algorithm packet.PublicKeyAlgorithm) bool {
+	if len(entry.AllowedRecipientKeyAlgorithms) == 0 {
+		return true
+	}
+	name, ok := recipientKeyAlgorithms[algorithm]
+	if !ok {
+		return false
+	}
+	return strutil.StrListContains(entry.AllowedRecipientKeyAlgorithms, name)
+}
+
+const pathKeysConfigHelpSyn = "Configure a named GPG key"
+const pathKeysConfigHelpDesc = `
+This path is used to configure the restrictions applied when the named
+GPG key is used. Reading the configuration requires the "read"
+capability and updating it the "update" capability.
+`
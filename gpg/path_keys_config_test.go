@@ -0,0 +1,121 @@
+package gpg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestGPG_KeysConfig(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig := func(keyName string, data map[string]interface{}, errExpected bool) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + keyName + "/config",
+			Data:      data,
+		})
+		if errExpected {
+			if !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	readConfig := func(keyName string, expected []string) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + keyName + "/config",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.IsError() {
+			t.Fatalf("not expected response: %#v", resp)
+		}
+		algorithms := resp.Data["allowed_recipient_key_algorithms"].([]string)
+		if !reflect.DeepEqual(algorithms, expected) {
+			t.Fatalf("expected algorithms %#v, got: %#v", expected, algorithms)
+		}
+	}
+
+	encrypt := func(keyName string, errExpected bool) {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/" + keyName,
+			Data: map[string]interface{}{
+				"plaintext":     "QWxwYWNhcwo=",
+				"recipient_key": gpgPublicKey,
+			},
+		})
+		if errExpected && !resp.IsError() {
+			t.Fatalf("expected error response: %#v", resp)
+		}
+		if !errExpected && resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	readConfig("test", nil)
+	encrypt("test", false)
+
+	writeConfig("test", map[string]interface{}{"allowed_recipient_key_algorithms": []string{"ecdsa", "EdDSA"}}, false)
+	readConfig("test", []string{"ecdsa", "eddsa"})
+	encrypt("test", true)
+
+	writeConfig("test", map[string]interface{}{"allowed_recipient_key_algorithms": []string{"rsa"}}, false)
+	readConfig("test", []string{"rsa"})
+	encrypt("test", false)
+
+	writeConfig("test", map[string]interface{}{"allowed_recipient_key_algorithms": []string{"notexisting"}}, true)
+	readConfig("test", []string{"rsa"})
+
+	writeConfig("doNotExist", map[string]interface{}{"allowed_recipient_key_algorithms": []string{"rsa"}}, true)
+
+	readAutoSign := func(expected bool) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test/config",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data["auto_sign_on_create"] != expected {
+			t.Fatalf("expected auto_sign_on_create %t, got: %#v", expected, resp.Data["auto_sign_on_create"])
+		}
+	}
+	readAutoSign(true)
+	writeConfig("test", map[string]interface{}{"auto_sign_on_create": false}, false)
+	readAutoSign(false)
+	// Other settings are left untouched
+	readConfig("test", []string{"rsa"})
+	writeConfig("test", map[string]interface{}{"allowed_recipient_key_algorithms": []string{"rsa"}}, false)
+	readAutoSign(false)
+}
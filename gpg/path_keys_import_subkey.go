@@ -0,0 +1,207 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func pathKeysImportSubkey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import-subkey",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+			"subkey": {
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored public or private key packet to add as an encryption subkey. A private key must not be encrypted.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Subkey", Sensitive: true, Group: "Input"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysImportSubkeyWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysImportSubkeyHelpSyn,
+		HelpDescription: pathKeysImportSubkeyHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysImportSubkeyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	publicKey, privateKey, bindingSig, err := readSubkey(data.Get("subkey").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if privateKey != nil && privateKey.Encrypted {
+		return logical.ErrorResponse("the subkey must not be encrypted"), logical.ErrInvalidRequest
+	}
+	// Signing subkeys require a cross signature the OpenPGP library cannot produce
+	if !publicKey.PubKeyAlgo.CanEncrypt() {
+		return logical.ErrorResponse("only encryption subkeys can be imported"), logical.ErrInvalidRequest
+	}
+	if isLegacyKeyAlgorithm(publicKey.PubKeyAlgo) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if !config.AllowLegacyKeyTypes {
+			return logical.ErrorResponse(errLegacyKeyTypesNotAllowed), logical.ErrInvalidRequest
+		}
+	}
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if entity.PrivateKey == nil {
+		return logical.ErrorResponse("the key has no private primary key"), logical.ErrInvalidRequest
+	}
+	if entity.PrimaryKey.Fingerprint == publicKey.Fingerprint {
+		return logical.ErrorResponse("subkey already exists"), logical.ErrInvalidRequest
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey.Fingerprint == publicKey.Fingerprint {
+			return logical.ErrorResponse("subkey already exists"), logical.ErrInvalidRequest
+		}
+	}
+
+	publicKey.IsSubkey = true
+	var sig *packet.Signature
+	if entry.DisableSubkeyAutoSign {
+		if bindingSig == nil {
+			return logical.ErrorResponse("auto_sign_on_create is disabled and the subkey has no binding signature"), logical.ErrInvalidRequest
+		}
+		if err := entity.PrimaryKey.VerifyKeySignature(publicKey, bindingSig); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid subkey binding signature: %s", err)), logical.ErrInvalidRequest
+		}
+		sig = bindingSig
+	} else {
+		if entity.PrivateKey.Encrypted {
+			return logical.ErrorResponse("the primary key cannot sign the subkey binding"), logical.ErrInvalidRequest
+		}
+		sig = &packet.Signature{
+			SigType:                   packet.SigTypeSubkeyBinding,
+			PubKeyAlgo:                entity.PrimaryKey.PubKeyAlgo,
+			Hash:                      crypto.SHA256,
+			CreationTime:              time.Now(),
+			IssuerKeyId:               &entity.PrimaryKey.KeyId,
+			FlagsValid:                true,
+			FlagEncryptStorage:        true,
+			FlagEncryptCommunications: true,
+		}
+		if err := sig.SignKey(publicKey, entity.PrivateKey, nil); err != nil {
+			return nil, err
+		}
+	}
+	entity.Subkeys = append(entity.Subkeys, openpgp.Subkey{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Sig:        sig,
+	})
+
+	var buf bytes.Buffer
+	if err := serializePrivateWithoutSigning(&buf, entity); err != nil {
+		return nil, err
+	}
+	entry.SerializedKey = buf.Bytes()
+	entry.Version = keyEntryVersion
+
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	if !entry.DisableSubkeyAutoSign {
+		b.logKeyEvent(req, keyEventAccessed, name)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"fingerprint": hex.EncodeToString(publicKey.Fingerprint[:]),
+		},
+	}
+	addKeyIdentifiers(resp.Data, publicKey)
+	return resp, nil
+}
+
+// readSubkey returns the first public or private key packet of an
+// ASCII-armored block, with the subkey binding signature following it. The
+// private key is nil when only a public key is present and the signature when
+// the key is not followed by a binding signature.
+func readSubkey(armored string) (*packet.PublicKey, *packet.PrivateKey, *packet.Signature, error) {
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	packets := packet.NewReader(block.Body)
+	var publicKey *packet.PublicKey
+	var privateKey *packet.PrivateKey
+	for publicKey == nil {
+		p, err := packets.Next()
+		if err == io.EOF {
+			return nil, nil, nil, fmt.Errorf("no key packet has been found")
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch p := p.(type) {
+		case *packet.PrivateKey:
+			publicKey, privateKey = &p.PublicKey, p
+		case *packet.PublicKey:
+			publicKey = p
+		}
+	}
+
+	p, err := packets.Next()
+	if err == io.EOF {
+		return publicKey, privateKey, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.SigType != packet.SigTypeSubkeyBinding {
+		return publicKey, privateKey, nil, nil
+	}
+	return publicKey, privateKey, sig, nil
+}
+
+const pathKeysImportSubkeyHelpSyn = "Add an externally generated subkey to a named GPG key"
+const pathKeysImportSubkeyHelpDesc = `
+This path adds the provided public or private key as an encryption subkey
+of the named GPG key. The binding signature of the subkey is issued by the
+primary key, unless auto_sign_on_create is disabled in the configuration
+of the key, in which case the subkey must be followed by its binding
+signature. It requires the "update" capability.
+`
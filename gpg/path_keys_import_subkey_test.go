@@ -0,0 +1,335 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGPG_KeysImportSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	external, err := openpgp.NewEntity("External", "", "external@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importSubkey := func(subkey string, errExpected bool) {
+		req.Path = "keys/test/import-subkey"
+		req.Data = map[string]interface{}{
+			"subkey": subkey,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if errExpected {
+			if err == nil && !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	var private bytes.Buffer
+	w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := external.Subkeys[0].PrivateKey.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	importSubkey(private.String(), false)
+	importSubkey(private.String(), true)
+
+	// Public only subkey
+	var public bytes.Buffer
+	w, err = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := external.PrimaryKey.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	importSubkey(public.String(), false)
+
+	// Signing only keys cannot be imported
+	importSubkey(testKeyFixture(t, "ecdsa-p256"), true)
+	importSubkey("not armored", true)
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := el[0]
+	if len(entity.Subkeys) != 3 {
+		t.Fatalf("expected 3 subkeys, got %d", len(entity.Subkeys))
+	}
+	for i, imported := range []*openpgp.Subkey{&entity.Subkeys[1], &entity.Subkeys[2]} {
+		expected := []uint64{external.Subkeys[0].PublicKey.KeyId, external.PrimaryKey.KeyId}[i]
+		if imported.PublicKey.KeyId != expected {
+			t.Fatalf("expected subkey %X, got %X", expected, imported.PublicKey.KeyId)
+		}
+		if err := entity.PrimaryKey.VerifyKeySignature(imported.PublicKey, imported.Sig); err != nil {
+			t.Fatalf("invalid binding signature: %s", err)
+		}
+		if !imported.Sig.FlagEncryptCommunications {
+			t.Fatal("expected an encryption subkey")
+		}
+	}
+
+	// The imported private subkey is usable for decryption
+	var ciphertext bytes.Buffer
+	recipient := &openpgp.Entity{
+		PrimaryKey: entity.PrimaryKey,
+		Identities: entity.Identities,
+		Subkeys:    entity.Subkeys[1:2],
+	}
+	pw, err := openpgp.Encrypt(&ciphertext, []*openpgp.Entity{recipient}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.Write([]byte("Alpacas\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "decrypt/test"
+	req.Data = map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString(ciphertext.Bytes()),
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["plaintext"] != "QWxwYWNhcwo=" {
+		t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %s", resp.Data["plaintext"])
+	}
+}
+
+func TestGPG_KeysImportSubkeyWithoutAutoSign(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	// The subkeys of the key are signed offline
+	if _, err := request("keys/test", map[string]interface{}{"generate": false, "key": gpgKey}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request("keys/test/config", map[string]interface{}{"auto_sign_on_create": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := el[0]
+	external, err := openpgp.NewEntity("External", "", "external@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subkey := func(signer *packet.PrivateKey) string {
+		pub := external.Subkeys[0].PublicKey
+		var buf bytes.Buffer
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pub.Serialize(w); err != nil {
+			t.Fatal(err)
+		}
+		if signer != nil {
+			sig := &packet.Signature{
+				SigType:                   packet.SigTypeSubkeyBinding,
+				PubKeyAlgo:                signer.PubKeyAlgo,
+				Hash:                      crypto.SHA256,
+				CreationTime:              time.Now(),
+				IssuerKeyId:               &signer.KeyId,
+				FlagsValid:                true,
+				FlagEncryptCommunications: true,
+			}
+			if err := sig.SignKey(pub, signer, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := sig.Serialize(w); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	for _, c := range []struct {
+		subkey        string
+		expectedError string
+	}{
+		{subkey(nil), "auto_sign_on_create is disabled and the subkey has no binding signature"},
+		// Binding signature issued by another key
+		{subkey(external.PrivateKey), "invalid subkey binding signature: "},
+	} {
+		resp, err := request("keys/test/import-subkey", map[string]interface{}{"subkey": c.subkey})
+		if err != logical.ErrInvalidRequest || !resp.IsError() || !strings.HasPrefix(resp.Error().Error(), c.expectedError) {
+			t.Fatalf("expected error response %q, got: %#v, %v", c.expectedError, resp, err)
+		}
+	}
+
+	resp, err := request("keys/test/import-subkey", map[string]interface{}{"subkey": subkey(primary.PrivateKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := el[0]
+	if len(entity.Subkeys) != 2 {
+		t.Fatalf("expected 2 subkeys, got %d", len(entity.Subkeys))
+	}
+	imported := entity.Subkeys[1]
+	if imported.PublicKey.KeyId != external.Subkeys[0].PublicKey.KeyId {
+		t.Fatalf("expected subkey %X, got %X", external.Subkeys[0].PublicKey.KeyId, imported.PublicKey.KeyId)
+	}
+	if err := entity.PrimaryKey.VerifyKeySignature(imported.PublicKey, imported.Sig); err != nil {
+		t.Fatalf("invalid binding signature: %s", err)
+	}
+}
+
+func TestGPG_KeysImportSubkeyPublicPrimaryKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	// Only the private key of the subkey is present, as exported by
+	// gpg --export-secret-subkeys
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "vault@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []interface{ Serialize(io.Writer) error }{
+		entity.PrimaryKey,
+		entity.Identities["Vault GPG test <vault@example.com>"].UserId,
+		entity.Identities["Vault GPG test <vault@example.com>"].SelfSignature,
+		entity.Subkeys[0].PrivateKey,
+		entity.Subkeys[0].Sig,
+	} {
+		if err := p.Serialize(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := request("keys/test", map[string]interface{}{"generate": false, "key": buf.String()})
+	if err != nil || resp.IsError() {
+		t.Fatalf("not expected response: %#v, %v", resp, err)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el[0].PrimaryKey.KeyId != entity.PrimaryKey.KeyId {
+		t.Fatalf("expected primary key %X, got %X", entity.PrimaryKey.KeyId, el[0].PrimaryKey.KeyId)
+	}
+
+	if _, err := request("keys/test/config", map[string]interface{}{"auto_sign_on_create": false}); err != nil {
+		t.Fatal(err)
+	}
+	external, err := openpgp.NewEntity("External", "", "external@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := external.Subkeys[0].PublicKey
+	sig := &packet.Signature{
+		SigType:                   packet.SigTypeSubkeyBinding,
+		PubKeyAlgo:                entity.PrivateKey.PubKeyAlgo,
+		Hash:                      crypto.SHA256,
+		CreationTime:              time.Now(),
+		IssuerKeyId:               &entity.PrivateKey.KeyId,
+		FlagsValid:                true,
+		FlagEncryptCommunications: true,
+	}
+	if err := sig.SignKey(pub, entity.PrivateKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	w, err = armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := sig.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request("keys/test/import-subkey", map[string]interface{}{"subkey": buf.String()})
+	if err != logical.ErrInvalidRequest || !resp.IsError() || resp.Error().Error() != "the key has no private primary key" {
+		t.Fatalf("expected error response, got: %#v, %v", resp, err)
+	}
+}
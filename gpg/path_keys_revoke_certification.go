@@ -0,0 +1,149 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// sigTypeCertificationRevocation is the certification revocation signature
+// type, it is not defined by the packet library.
+const sigTypeCertificationRevocation packet.SignatureType = 0x30
+
+func pathKeysRevokeCertification(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/revoke-certification",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key revoking its certification.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+			"target_key_name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key whose identity certification is revoked.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Target key name", Group: "Key Settings"},
+			},
+			"target_uid_index": {
+				Type:         framework.TypeInt,
+				Description:  "Index of the identity of the target key, in the order of its user ID packets. Defaults to 0.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Target user ID index", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysRevokeCertificationWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysRevokeCertificationHelpSyn,
+		HelpDescription: pathKeysRevokeCertificationHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysRevokeCertificationWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	targetName := data.Get("target_key_name").(string)
+	uidIndex := data.Get("target_uid_index").(int)
+	if targetName == "" {
+		return logical.ErrorResponse("target_key_name is required"), logical.ErrInvalidRequest
+	}
+	if targetName == name {
+		return logical.ErrorResponse("a key cannot revoke the certification of its own identities"), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+	signer, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if signer.PrivateKey == nil {
+		return logical.ErrorResponse("key is public-key-only; cannot perform private key operations"), logical.ErrInvalidRequest
+	}
+
+	targetEntry, err := b.key(ctx, req.Storage, targetName)
+	if err != nil {
+		return nil, err
+	}
+	if targetEntry == nil {
+		return logical.ErrorResponse("target key not found"), logical.ErrInvalidRequest
+	}
+	target, err := b.entity(targetEntry)
+	if err != nil {
+		return nil, err
+	}
+	uids, err := userIDs(targetEntry.SerializedKey)
+	if err != nil {
+		return nil, err
+	}
+	if uidIndex < 0 || uidIndex >= len(uids) {
+		return logical.ErrorResponse(fmt.Sprintf("target_uid_index must be between 0 and %d", len(uids)-1)), logical.ErrInvalidRequest
+	}
+	ident, ok := target.Identities[uids[uidIndex]]
+	if !ok {
+		return nil, fmt.Errorf("identity %d of the target key not found", uidIndex)
+	}
+
+	sig := &packet.Signature{
+		SigType:      sigTypeCertificationRevocation,
+		PubKeyAlgo:   signer.PrimaryKey.PubKeyAlgo,
+		Hash:         crypto.SHA256,
+		CreationTime: time.Now(),
+		IssuerKeyId:  &signer.PrimaryKey.KeyId,
+	}
+	if err := sig.SignUserId(ident.Name, target.PrimaryKey, signer.PrivateKey, nil); err != nil {
+		return nil, err
+	}
+	ident.Signatures = append(ident.Signatures, sig)
+
+	publicKey, err := armoredPublicKey(target)
+	if err != nil {
+		return nil, err
+	}
+	b.logKeyEvent(req, keyEventAccessed, name)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
+// userIDs returns the user IDs of a serialized key in the order of their
+// packets, which is lost in the identities of an entity.
+func userIDs(serializedKey []byte) ([]string, error) {
+	var uids []string
+	packets := packet.NewReader(bytes.NewReader(serializedKey))
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			return uids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if uid, ok := p.(*packet.UserId); ok {
+			uids = append(uids, uid.Id)
+		}
+	}
+}
+
+const pathKeysRevokeCertificationHelpSyn = "Revoke the certification of an identity of another named GPG key"
+const pathKeysRevokeCertificationHelpDesc = `
+This path revokes the certification of an identity of the target GPG key
+by the named GPG key, with a certification revocation signature. The
+stored keys are left untouched, the ASCII-armored public key of the
+target holding the revocation is returned for publication. It requires
+the "update" capability.
+`
@@ -0,0 +1,74 @@
+package gpg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestGPG_KeysRevokeCertification(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	for _, name := range []string{"signer", "target"} {
+		if _, err := request("keys/"+name, map[string]interface{}{"real_name": "Vault GPG " + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := request("keys/signer/revoke-certification", map[string]interface{}{
+		"target_key_name": "target",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "signer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := el[0].PrimaryKey
+	el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := el[0]
+	ident, ok := target.Identities["Vault GPG target"]
+	if !ok || len(ident.Signatures) != 1 {
+		t.Fatalf("expected 1 signature on the identity: %#v", target.Identities)
+	}
+	sig := ident.Signatures[0]
+	if sig.SigType != sigTypeCertificationRevocation {
+		t.Fatalf("expected a certification revocation, got signature type %#x", sig.SigType)
+	}
+	if err := signer.VerifyUserIdSignature(ident.Name, target.PrimaryKey, sig); err != nil {
+		t.Fatalf("invalid revocation signature: %s", err)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{"target_key_name": "signer"},
+		{"target_key_name": "doNotExist"},
+		{"target_key_name": "target", "target_uid_index": 1},
+		{"target_key_name": "target", "target_uid_index": -1},
+	} {
+		resp, err := request("keys/signer/revoke-certification", data)
+		if err != logical.ErrInvalidRequest || !resp.IsError() {
+			t.Fatalf("%#v: expected error response, got: %#v, %v", data, resp, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const maxTrustAmount = 120
+
+func pathKeysSignKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/sign-key",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key issuing the trust signature.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+			"target_key_name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key whose identities are trust signed.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Target key name", Group: "Key Settings"},
+			},
+			"trust_depth": {
+				Type:         framework.TypeInt,
+				Default:      1,
+				Description:  "Depth of the trust delegation, from 0 to 255. A depth of 1 makes the target key a trusted introducer. Defaults to 1.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Trust depth", Group: "Key Settings"},
+			},
+			"trust_amount": {
+				Type:         framework.TypeInt,
+				Default:      maxTrustAmount,
+				Description:  "Amount of trust delegated, from 0 to 120. 60 means partial trust and 120 complete trust. Defaults to 120.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Trust amount", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysSignKeyWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysSignKeyHelpSyn,
+		HelpDescription: pathKeysSignKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysSignKeyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	targetName := data.Get("target_key_name").(string)
+	trustDepth := data.Get("trust_depth").(int)
+	trustAmount := data.Get("trust_amount").(int)
+	if targetName == "" {
+		return logical.ErrorResponse("target_key_name is required"), logical.ErrInvalidRequest
+	}
+	if targetName == name {
+		return logical.ErrorResponse("a key cannot trust sign itself"), logical.ErrInvalidRequest
+	}
+	if trustDepth < 0 || trustDepth > 255 {
+		return logical.ErrorResponse("trust_depth must be between 0 and 255"), logical.ErrInvalidRequest
+	}
+	if trustAmount < 0 || trustAmount > maxTrustAmount {
+		return logical.ErrorResponse(fmt.Sprintf("trust_amount must be between 0 and %d", maxTrustAmount)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+	signer, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if signer.PrivateKey == nil {
+		return logical.ErrorResponse("key is public-key-only; cannot perform private key operations"), logical.ErrInvalidRequest
+	}
+	switch signer.PrivateKey.PrivateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return logical.ErrorResponse("only RSA and ECDSA keys can issue trust signatures"), logical.ErrInvalidRequest
+	}
+
+	targetEntry, err := b.key(ctx, req.Storage, targetName)
+	if err != nil {
+		return nil, err
+	}
+	if targetEntry == nil {
+		return logical.ErrorResponse("target key not found"), logical.ErrInvalidRequest
+	}
+	target, err := b.entity(targetEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, ident := range target.Identities {
+		sig, err := trustSignature(signer, target.PrimaryKey, ident.Name, uint8(trustDepth), uint8(trustAmount), now)
+		if err != nil {
+			return nil, err
+		}
+		ident.Signatures = append(ident.Signatures, sig)
+	}
+
+	publicKey, err := armoredPublicKey(target)
+	if err != nil {
+		return nil, err
+	}
+	b.logKeyEvent(req, keyEventAccessed, name)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
+// trustSignature returns the generic certification of the identity of the
+// public key by the signer, with a trust signature subpacket. The packet
+// library cannot add this subpacket so the signature packet is built here and
+// parsed back.
+func trustSignature(signer *openpgp.Entity, pub *packet.PublicKey, id string, depth, amount uint8, now time.Time) (*packet.Signature, error) {
+	var hashed bytes.Buffer
+	hashed.Write([]byte{5, creationTimeSubpacket})
+	binary.Write(&hashed, binary.BigEndian, uint32(now.Unix()))
+	hashed.Write([]byte{9, issuerSubpacket})
+	binary.Write(&hashed, binary.BigEndian, signer.PrimaryKey.KeyId)
+	hashed.Write([]byte{3, trustSignatureSubpacket, depth, amount})
+
+	// RFC 4880, section 5.2.4
+	var suffix bytes.Buffer
+	suffix.Write([]byte{4, byte(packet.SigTypeGenericCert), byte(signer.PrimaryKey.PubKeyAlgo), hashIDSHA256})
+	binary.Write(&suffix, binary.BigEndian, uint16(hashed.Len()))
+	suffix.Write(hashed.Bytes())
+
+	var key bytes.Buffer
+	if err := pub.Serialize(&key); err != nil {
+		return nil, err
+	}
+	var prefix bytes.Buffer
+	pub.SerializeSignaturePrefix(&prefix)
+	bodyLen := int(binary.BigEndian.Uint16(prefix.Bytes()[1:]))
+
+	h := crypto.SHA256.New()
+	h.Write(prefix.Bytes())
+	h.Write(key.Bytes()[key.Len()-bodyLen:])
+	h.Write([]byte{0xb4})
+	binary.Write(h, binary.BigEndian, uint32(len(id)))
+	h.Write([]byte(id))
+	h.Write(suffix.Bytes())
+	h.Write([]byte{4, 0xff})
+	binary.Write(h, binary.BigEndian, uint32(suffix.Len()))
+	digest := h.Sum(nil)
+
+	var mpis []*big.Int
+	switch priv := signer.PrivateKey.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest)
+		if err != nil {
+			return nil, err
+		}
+		mpis = []*big.Int{new(big.Int).SetBytes(s)}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+		if err != nil {
+			return nil, err
+		}
+		mpis = []*big.Int{r, s}
+	default:
+		return nil, fmt.Errorf("unsupported signing key algorithm %d", signer.PrimaryKey.PubKeyAlgo)
+	}
+
+	var body bytes.Buffer
+	body.Write(suffix.Bytes())
+	body.Write([]byte{0, 0}) // No unhashed subpacket
+	body.Write(digest[:2])
+	for _, mpi := range mpis {
+		binary.Write(&body, binary.BigEndian, uint16(mpi.BitLen()))
+		body.Write(mpi.Bytes())
+	}
+
+	// New format signature packet header with a five-octet length
+	var sigPacket bytes.Buffer
+	sigPacket.Write([]byte{0xc2, 0xff})
+	binary.Write(&sigPacket, binary.BigEndian, uint32(body.Len()))
+	sigPacket.Write(body.Bytes())
+
+	p, err := packet.Read(&sigPacket)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T packet", p)
+	}
+	if err := signer.PrimaryKey.VerifyUserIdSignature(id, pub, sig); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+const pathKeysSignKeyHelpSyn = "Trust sign another named GPG key"
+const pathKeysSignKeyHelpDesc = `
+This path certifies the identities of the target GPG key with the named
+GPG key, using trust signatures delegating the given trust depth and
+amount. The stored keys are left untouched, the ASCII-armored public key
+of the target holding the trust signatures is returned. It requires the
+"update" capability.
+`
@@ -0,0 +1,100 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestGPG_KeysSignKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	for name, data := range map[string]map[string]interface{}{
+		"signer": {"real_name": "Vault GPG signer"},
+		"target": {"real_name": "Vault GPG target"},
+		"ecdsa":  {"generate": false, "key": testKeyFixture(t, "ecdsa-p256")},
+	} {
+		if _, err := request("keys/"+name, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, signerName := range []string{"signer", "ecdsa"} {
+		resp, err := request("keys/"+signerName+"/sign-key", map[string]interface{}{
+			"target_key_name": "target",
+			"trust_amount":    60,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", signerName, *resp)
+		}
+
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, signerName)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := el[0].PrimaryKey
+		el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := el[0]
+		for name, ident := range target.Identities {
+			if len(ident.Signatures) != 1 {
+				t.Fatalf("%s: expected 1 signature on %s, got %d", signerName, name, len(ident.Signatures))
+			}
+			sig := ident.Signatures[0]
+			if err := signer.VerifyUserIdSignature(name, target.PrimaryKey, sig); err != nil {
+				t.Fatalf("%s: invalid trust signature: %s", signerName, err)
+			}
+			// Trust signature subpacket of depth 1 and amount 60
+			if !bytes.Contains(sig.HashSuffix, []byte{3, 5, 1, 60}) {
+				t.Fatalf("%s: no trust signature subpacket found", signerName)
+			}
+		}
+	}
+
+	// The stored target key is left untouched
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(testPublicKey(t, b, storage, "target")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ident := range el[0].Identities {
+		if len(ident.Signatures) != 0 {
+			t.Fatal("the stored target key was modified")
+		}
+	}
+
+	for _, c := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"keys/signer/sign-key", map[string]interface{}{}},
+		{"keys/signer/sign-key", map[string]interface{}{"target_key_name": "signer"}},
+		{"keys/signer/sign-key", map[string]interface{}{"target_key_name": "doNotExist"}},
+		{"keys/doNotExist/sign-key", map[string]interface{}{"target_key_name": "target"}},
+		{"keys/signer/sign-key", map[string]interface{}{"target_key_name": "target", "trust_depth": 256}},
+		{"keys/signer/sign-key", map[string]interface{}{"target_key_name": "target", "trust_amount": 121}},
+		{"keys/signer/sign-key", map[string]interface{}{"target_key_name": "target", "trust_amount": -1}},
+	} {
+		resp, err := request(c.path, c.data)
+		if err != logical.ErrInvalidRequest || !resp.IsError() {
+			t.Fatalf("%s %#v: expected error response, got: %#v, %v", c.path, c.data, resp, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func pathKeysSubkeyExtendExpiry(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/subkeys/(?P<fingerprint>[0-9a-fA-F]{40})/extend-expiry",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+			"fingerprint": {
+				Type:         framework.TypeString,
+				Description:  "Fingerprint of the subkey.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Fingerprint", Group: "Key Settings"},
+			},
+			"expires_in": {
+				Type:         framework.TypeDurationSecond,
+				Description:  "Duration from now after which the subkey expires. Defaults to 0, meaning the subkey never expires.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Expires in", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysSubkeyExtendExpiryWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysSubkeyExtendExpiryHelpSyn,
+		HelpDescription: pathKeysSubkeyExtendExpiryHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysSubkeyExtendExpiryWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	fingerprint := strings.ToLower(data.Get("fingerprint").(string))
+	expiresIn := time.Duration(data.Get("expires_in").(int)) * time.Second
+	if expiresIn < 0 {
+		return logical.ErrorResponse("expires_in must be positive"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if entity.PrivateKey == nil || entity.PrivateKey.Encrypted {
+		return logical.ErrorResponse("the primary key cannot sign the subkey binding"), logical.ErrInvalidRequest
+	}
+
+	index := -1
+	for i, subkey := range entity.Subkeys {
+		if hex.EncodeToString(subkey.PublicKey.Fingerprint[:]) == fingerprint {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return logical.ErrorResponse("subkey not found"), logical.ErrInvalidRequest
+	}
+	subkey := entity.Subkeys[index]
+	if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation {
+		return logical.ErrorResponse("the subkey is revoked"), logical.ErrInvalidRequest
+	}
+	// The cross signature of signing subkeys would be lost by the OpenPGP library
+	if subkey.Sig.FlagSign {
+		return logical.ErrorResponse("the expiry of signing subkeys cannot be extended"), logical.ErrInvalidRequest
+	}
+	if !canSignAgain(subkey.Sig) {
+		return logical.ErrorResponse("the binding signature of the subkey holds data that would be lost when signed again"), logical.ErrInvalidRequest
+	}
+
+	now := time.Now()
+	if expiresIn == 0 {
+		subkey.Sig.KeyLifetimeSecs = nil
+	} else {
+		if subkey.Sig.KeyLifetimeSecs == nil {
+			return logical.ErrorResponse("the subkey never expires"), logical.ErrInvalidRequest
+		}
+		expiration := now.Add(expiresIn)
+		current := subkey.PublicKey.CreationTime.Add(time.Duration(*subkey.Sig.KeyLifetimeSecs) * time.Second)
+		if !expiration.After(current) {
+			return logical.ErrorResponse("expires_in must extend the current expiry of the subkey"), logical.ErrInvalidRequest
+		}
+		lifetime := expiration.Sub(subkey.PublicKey.CreationTime) / time.Second
+		if lifetime > math.MaxUint32 {
+			return logical.ErrorResponse("expires_in is too large"), logical.ErrInvalidRequest
+		}
+		keyLifetimeSecs := uint32(lifetime)
+		subkey.Sig.KeyLifetimeSecs = &keyLifetimeSecs
+	}
+	subkey.Sig.CreationTime = now
+	if err := subkey.Sig.SignKey(subkey.PublicKey, entity.PrivateKey, nil); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := serializePrivateWithoutSigning(&buf, entity); err != nil {
+		return nil, err
+	}
+	entry.SerializedKey = buf.Bytes()
+	entry.Version = keyEntryVersion
+
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	b.logKeyEvent(req, keyEventAccessed, name)
+
+	return &logical.Response{
+		Data: subkeyMetadata(subkey, now),
+	}, nil
+}
+
+const pathKeysSubkeyExtendExpiryHelpSyn = "Extend the expiry of a subkey of a named GPG key"
+const pathKeysSubkeyExtendExpiryHelpDesc = `
+This path updates the expiration time of the subkey identified by its
+fingerprint and signs again its binding with the primary key. The new
+expiration time must be later than the current one. The primary key and the
+other subkeys are left untouched. It requires the "update" capability.
+`
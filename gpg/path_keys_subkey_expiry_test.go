@@ -0,0 +1,108 @@
+package gpg
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGPG_KeysSubkeyExtendExpiry(t *testing.T) {
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifetime := uint32((24 * time.Hour).Seconds())
+	expired := testSubkey(t, entity, time.Now().Add(-48*time.Hour), packet.SigTypeSubkeyBinding)
+	expired.Sig.KeyLifetimeSecs = &lifetime
+	revoked := testSubkey(t, entity, time.Now(), packet.SigTypeSubkeyRevocation)
+	entity.Subkeys = append(entity.Subkeys, expired, revoked)
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      testArmoredPrivateKey(t, entity),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extend := func(name string, subkey openpgp.Subkey, expiresIn string, errExpected bool) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name + "/subkeys/" + hex.EncodeToString(subkey.PublicKey.Fingerprint[:]) + "/extend-expiry",
+			Data: map[string]interface{}{
+				"expires_in": expiresIn,
+			},
+		})
+		if errExpected {
+			if err == nil && !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return resp
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		return resp
+	}
+
+	resp := extend("test", expired, "1h", false)
+	if resp.Data["expired"] != false || resp.Data["expiration_time"] == "" {
+		t.Fatalf("expected the subkey expiry to be extended: %#v", resp.Data)
+	}
+	expiration, err := time.Parse(time.RFC3339, resp.Data["expiration_time"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expiration); d < 59*time.Minute || d > 61*time.Minute {
+		t.Fatalf("expected the subkey to expire in one hour, got %s", expiration)
+	}
+
+	// The expiry can only be extended
+	extend("test", expired, "30m", true)
+	extend("test", expired, "2h", false)
+	extend("test", entity.Subkeys[0], "1h", true)
+
+	resp = extend("test", entity.Subkeys[0], "0", false)
+	if resp.Data["expiration_time"] != "" {
+		t.Fatalf("expected the subkey to never expire: %#v", resp.Data)
+	}
+
+	extend("test", revoked, "1h", true)
+	extend("test", entity.Subkeys[0], "-1h", true)
+	extend("missing", expired, "1h", true)
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extend("test", other.Subkeys[0], "1h", true)
+
+	// The stored key is signed again and can still be read
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test/subkeys",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subkeys := resp.Data["subkeys"].([]map[string]interface{})
+	if subkeys[0]["expiration_time"] != "" || subkeys[1]["expired"] != false || subkeys[2]["revoked"] != true {
+		t.Fatalf("unexpected subkeys %#v", subkeys)
+	}
+}
@@ -0,0 +1,111 @@
+package gpg
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func pathKeysSubkeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/subkeys/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathKeysSubkeysRead,
+			},
+		},
+		HelpSynopsis:    pathKeysSubkeysHelpSyn,
+		HelpDescription: pathKeysSubkeysHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysSubkeysRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	subkeys := make([]map[string]interface{}, 0, len(entity.Subkeys))
+	for _, subkey := range entity.Subkeys {
+		subkeys = append(subkeys, subkeyMetadata(subkey, now))
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"subkeys": subkeys,
+		},
+	}, nil
+}
+
+// subkeyMetadata describes a subkey. The usage and the expiration of a revoked
+// subkey are unknown since its binding signature is replaced by the revocation.
+func subkeyMetadata(subkey openpgp.Subkey, now time.Time) map[string]interface{} {
+	revoked := subkey.Sig.SigType == packet.SigTypeSubkeyRevocation
+
+	usage := []string{}
+	expirationTime := ""
+	if !revoked {
+		if subkey.Sig.FlagCertify {
+			usage = append(usage, "certify")
+		}
+		if subkey.Sig.FlagSign {
+			usage = append(usage, "sign")
+		}
+		if subkey.Sig.FlagEncryptCommunications {
+			usage = append(usage, "encrypt_communications")
+		}
+		if subkey.Sig.FlagEncryptStorage {
+			usage = append(usage, "encrypt_storage")
+		}
+		if subkey.Sig.KeyLifetimeSecs != nil && *subkey.Sig.KeyLifetimeSecs != 0 {
+			expiration := subkey.PublicKey.CreationTime.Add(time.Duration(*subkey.Sig.KeyLifetimeSecs) * time.Second)
+			expirationTime = expiration.UTC().Format(time.RFC3339)
+		}
+	}
+
+	algorithm, ok := recipientKeyAlgorithms[subkey.PublicKey.PubKeyAlgo]
+	if !ok {
+		algorithm = "unknown"
+	}
+
+	metadata := map[string]interface{}{
+		"fingerprint":     hex.EncodeToString(subkey.PublicKey.Fingerprint[:]),
+		"key_id":          hex.EncodeToString(subkey.PublicKey.Fingerprint[12:]),
+		"algorithm":       algorithm,
+		"usage":           usage,
+		"creation_time":   subkey.PublicKey.CreationTime.UTC().Format(time.RFC3339),
+		"expiration_time": expirationTime,
+		"expired":         !revoked && subkey.Sig.KeyExpired(now),
+		"revoked":         revoked,
+		"has_private_key": subkey.PrivateKey != nil,
+	}
+	addKeyIdentifiers(metadata, subkey.PublicKey)
+	return metadata
+}
+
+const pathKeysSubkeysHelpSyn = "List the subkeys of a named GPG key"
+const pathKeysSubkeysHelpDesc = `
+This path lists all the subkeys of the named GPG key, including the
+expired and revoked ones, with their algorithm, usage, creation and
+expiration times. It requires the "read" capability.
+`
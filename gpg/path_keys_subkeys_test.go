@@ -0,0 +1,120 @@
+package gpg
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGPG_KeysSubkeys(t *testing.T) {
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creationTime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	lifetime := uint32((24 * time.Hour).Seconds())
+	expired := testSubkey(t, entity, creationTime, packet.SigTypeSubkeyBinding)
+	expired.Sig.KeyLifetimeSecs = &lifetime
+	revoked := testSubkey(t, entity, creationTime, packet.SigTypeSubkeyRevocation)
+	entity.Subkeys = append(entity.Subkeys, expired, revoked)
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      testArmoredPrivateKey(t, entity),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test/subkeys",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() {
+		t.Fatalf("not expected response: %#v", resp)
+	}
+	subkeys := resp.Data["subkeys"].([]map[string]interface{})
+	if len(subkeys) != 3 {
+		t.Fatalf("expected 3 subkeys, got %d", len(subkeys))
+	}
+
+	valid := subkeys[0]
+	if valid["fingerprint"] != hex.EncodeToString(entity.Subkeys[0].PublicKey.Fingerprint[:]) {
+		t.Fatalf("unexpected fingerprint %s", valid["fingerprint"])
+	}
+	if valid["algorithm"] != "rsa" || valid["expired"] != false || valid["revoked"] != false || valid["expiration_time"] != "" {
+		t.Fatalf("unexpected subkey metadata %#v", valid)
+	}
+	usage := valid["usage"].([]string)
+	if len(usage) != 2 || usage[0] != "encrypt_communications" || usage[1] != "encrypt_storage" {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+
+	if subkeys[1]["expired"] != true || subkeys[1]["revoked"] != false {
+		t.Fatalf("expected expired subkey %#v", subkeys[1])
+	}
+	if subkeys[1]["creation_time"] != creationTime.UTC().Format(time.RFC3339) ||
+		subkeys[1]["expiration_time"] != creationTime.Add(24*time.Hour).UTC().Format(time.RFC3339) {
+		t.Fatalf("unexpected times %#v", subkeys[1])
+	}
+	if subkeys[2]["revoked"] != true || subkeys[2]["expired"] != false {
+		t.Fatalf("expected revoked subkey %#v", subkeys[2])
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/missing/subkeys",
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected no response for a missing key: %#v %v", resp, err)
+	}
+}
+
+// testSubkey generates an RSA encryption subkey whose signature, of the given
+// type, is signed by the entity when it is serialized.
+func testSubkey(t *testing.T, e *openpgp.Entity, creationTime time.Time, sigType packet.SignatureType) openpgp.Subkey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subkey := openpgp.Subkey{
+		PublicKey:  packet.NewRSAPublicKey(creationTime, &key.PublicKey),
+		PrivateKey: packet.NewRSAPrivateKey(creationTime, key),
+		Sig: &packet.Signature{
+			CreationTime:              creationTime,
+			SigType:                   sigType,
+			PubKeyAlgo:                packet.PubKeyAlgoRSA,
+			Hash:                      crypto.SHA256,
+			FlagsValid:                true,
+			FlagEncryptStorage:        true,
+			FlagEncryptCommunications: true,
+			IssuerKeyId:               &e.PrimaryKey.KeyId,
+		},
+	}
+	subkey.PublicKey.IsSubkey = true
+	subkey.PrivateKey.IsSubkey = true
+	return subkey
+}
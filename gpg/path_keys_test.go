@@ -2,8 +2,10 @@ package gpg
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
+	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -131,6 +133,90 @@ func TestGPG_CreateErrorGeneratedKeyTooSmallKeyBits(t *testing.T) {
 	}
 }
 
+func TestGPG_SearchKeys(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	createKey := func(name string, data map[string]interface{}) {
+		response, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.IsError() {
+			t.Fatal(response.Error())
+		}
+	}
+	createKey("generated", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"email":     "Vault@example.com",
+	})
+	createKey("imported", map[string]interface{}{
+		"generate": false,
+		"key":      gpgKey,
+	})
+
+	search := func(data map[string]interface{}, expected []string) {
+		response, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/search",
+			Data:      data,
+		})
+		if expected == nil {
+			if !response.IsError() {
+				t.Fatalf("expected error response: %#v", response)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.IsError() {
+			t.Fatalf("not expected error response: %#v", *response)
+		}
+		keys, _ := response.Data["keys"].([]string)
+		if len(expected) == 0 && len(keys) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected keys %#v, got: %#v", expected, keys)
+		}
+		keyInfo := response.Data["key_info"].(map[string]interface{})
+		for _, name := range keys {
+			if _, ok := keyInfo[name].(map[string]interface{})["fingerprint"]; !ok {
+				t.Fatalf("no fingerprint found for %s in %#v", name, keyInfo)
+			}
+		}
+	}
+
+	search(map[string]interface{}{"email": "vault@EXAMPLE.com"}, []string{"generated", "imported"})
+	search(map[string]interface{}{"name_substring": "gpg"}, []string{"generated"})
+	search(map[string]interface{}{"email": "vault@example.com", "name_substring": "vault"}, []string{"generated", "imported"})
+	search(map[string]interface{}{"fingerprint_prefix": "FBBC9A77"}, []string{"imported"})
+	search(map[string]interface{}{"fingerprint_prefix": "fbbc9a77", "name_substring": "gpg"}, []string{})
+	search(map[string]interface{}{"email": "unknown@example.com"}, []string{})
+	search(map[string]interface{}{}, nil)
+
+	// The search path shadows the key named search, its creation is refused
+	// whatever the route
+	resp, err := b.pathKeyCreate(context.Background(), &logical.Request{Storage: storage}, &framework.FieldData{
+		Raw:    map[string]interface{}{"name": reservedKeyName, "real_name": "Vault GPG test"},
+		Schema: pathKeys(b).Fields,
+	})
+	if err != nil || !resp.IsError() || resp.Error().Error() != "the key name search is reserved" {
+		t.Fatalf("expected reserved name error response, got: %#v, %v", resp, err)
+	}
+	if entry, err := b.key(context.Background(), storage, reservedKeyName); err != nil || entry != nil {
+		t.Fatalf("not expected key: %#v, %v", entry, err)
+	}
+}
+
 const gpgPublicKey = `-----BEGIN PGP PUBLIC KEY BLOCK-----
 
 mQENBFmZfJIBCACx2NgAf4rLLx2QKo444ATs3ewJICdy/cYhETxcn5wewdrxQayJ
@@ -161,3 +247,106 @@ qKqjtePx5gnMoyVXnDqgwsbxPh6GdKGx+Rgt47o1bXm/o8PSpA5Qbb3xVKmAi34b
 ZfOYAeX554UB1xwK6a/T3rHf3eZM4Oc64dsmbhRftQ==
 =G71q
 -----END PGP PUBLIC KEY BLOCK-----`
+
+func TestGPG_ReadKeyIdentifiers(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Operation = logical.ReadOperation
+	req.Data = nil
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"fingerprint":    "fbbc9a77bb696e6787ef0b5b2f7b5633b6f42527",
+		"fingerprint_v4": "fbbc9a77bb696e6787ef0b5b2f7b5633b6f42527",
+		"key_id_long":    "2f7b5633b6f42527",
+		"key_id_short":   "b6f42527",
+	}
+	for field, value := range expected {
+		if resp.Data[field] != value {
+			t.Fatalf("expected %s %s, got: %s", field, value, resp.Data[field])
+		}
+	}
+}
+
+func TestGPG_ImportKeyFixtures(t *testing.T) {
+	cases := []struct {
+		keyType     string
+		expectError bool
+	}{
+		{"rsa-2048", false},
+		{"rsa-4096", false},
+		{"ecdsa-p256", false},
+		// EdDSA keys are not supported by the OpenPGP library
+		{"ed25519", true},
+	}
+
+	for _, c := range cases {
+		storage := &logical.InmemStorage{}
+		b := Backend()
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data: map[string]interface{}{
+				"generate": false,
+				"key":      testKeyFixture(t, c.keyType),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.expectError {
+			if !resp.IsError() {
+				t.Fatalf("%s: expected error response: %#v", c.keyType, resp)
+			}
+			continue
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", c.keyType, *resp)
+		}
+
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input": "QWxwYWNhcwo=",
+			},
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", c.keyType, *resp)
+		}
+
+		req.Path = "verify/test"
+		req.Data["signature"] = resp.Data["signature"]
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() || !resp.Data["valid"].(bool) {
+			t.Fatalf("%s: expected valid signature: %#v", c.keyType, *resp)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package gpg
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathKeysWipe(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/wipe",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:         framework.TypeString,
+				Description:  "Name of the key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeysWipeWrite,
+			},
+		},
+		HelpSynopsis:    pathKeysWipeHelpSyn,
+		HelpDescription: pathKeysWipeHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysWipeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := req.Storage.Get(ctx, "key/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+
+	if _, err := rand.Read(entry.Value); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete(ctx, "key/"+name); err != nil {
+		return nil, err
+	}
+	b.logKeyEvent(req, keyEventDeleted, name)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"wipe_time": time.Now().UTC().Format(time.RFC3339),
+		},
+	}, nil
+}
+
+const pathKeysWipeHelpSyn = "Overwrite and delete a named GPG key"
+const pathKeysWipeHelpDesc = `
+This path overwrites the stored named GPG key with random data before
+deleting it. Whether the previous content is physically erased depends
+on the storage backend of Vault. It requires the "update" capability.
+`
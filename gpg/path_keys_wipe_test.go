@@ -0,0 +1,74 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// putRecordingStorage records the values written to the storage.
+type putRecordingStorage struct {
+	logical.InmemStorage
+	puts [][]byte
+}
+
+func (s *putRecordingStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	s.puts = append(s.puts, append([]byte(nil), entry.Value...))
+	return s.InmemStorage.Put(ctx, entry)
+}
+
+func TestGPG_KeysWipe(t *testing.T) {
+	storage := &putRecordingStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := storage.Get(context.Background(), "key/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := append([]byte(nil), stored.Value...)
+
+	req.Path = "keys/test/wipe"
+	req.Data = nil
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if _, err := time.Parse(time.RFC3339, resp.Data["wipe_time"].(string)); err != nil {
+		t.Fatalf("invalid wipe_time: %s", err)
+	}
+
+	overwritten := storage.puts[len(storage.puts)-1]
+	if len(overwritten) != len(original) || bytes.Equal(overwritten, original) {
+		t.Fatal("expected the key to be overwritten with random data before deletion")
+	}
+	entry, err := storage.Get(context.Background(), "key/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatal("expected the key to be deleted")
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected key not found error response: %#v", resp)
+	}
+}
@@ -22,21 +22,25 @@ func pathShowSessionKey(b *backend) *framework.Path {
 		Pattern: "show-session-key/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
+				Type:         framework.TypeString,
+				Description:  "The key to use",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"ciphertext": {
-				Type:        framework.TypeString,
-				Description: "The ciphertext to decrypt",
+				Type:         framework.TypeString,
+				Description:  "The ciphertext to decrypt",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Ciphertext", Group: "Input"},
 			},
 			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format the ciphertext uses. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Type:         framework.TypeString,
+				Default:      "base64",
+				Description:  `Encoding format the ciphertext uses. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Format", Group: "Output"},
 			},
 			"signer_key": {
-				Type:        framework.TypeString,
-				Description: "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Signer key", Group: "Key Settings"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -58,11 +62,13 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
 	}
 
-	keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	name := data.Get("name").(string)
+	keyEntry, err := b.key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if keyEntry == nil {
+		b.logOperationFailure(req, "session key decryption", name, "key_not_found")
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
 
@@ -71,14 +77,18 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 	if err != nil {
 		return nil, err
 	}
+	if keyring[0].PrivateKey == nil {
+		b.logOperationFailure(req, "session key decryption", name, "public_key_only")
+		return logical.ErrorResponse("key is public-key-only; cannot perform private key operations"), logical.ErrInvalidRequest
+	}
 
 	signerKey := data.Get("signer_key").(string)
 	if signerKey != "" {
-		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(signerKey))
+		signer, err := readArmoredKey(signerKey)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
-		keyring = append(keyring, el[0])
+		keyring = append(keyring, signer)
 	}
 
 	ciphertextEncoded := strings.NewReader(data.Get("ciphertext").(string))
@@ -89,6 +99,7 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 	case "ascii-armor":
 		block, err := armor.Decode(ciphertextEncoded)
 		if err != nil {
+			b.logOperationFailure(req, "session key decryption", name, "invalid_encoding")
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 		ciphertextDecoder = block.Body
@@ -99,9 +110,11 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 	for {
 		p, err = packet.Read(ciphertextDecoder)
 		if err == io.EOF {
+			b.logOperationFailure(req, "session key decryption", name, "decryption_failed")
 			return logical.ErrorResponse("Unable to decrypt session key"), nil
 		}
 		if err != nil {
+			b.logOperationFailure(req, "session key decryption", name, "invalid_ciphertext")
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 		switch p := p.(type) {
@@ -113,6 +126,7 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 
 				if encryptedKey.Key != nil && len(encryptedKey.Key) > 0 {
 					sessionKey = fmt.Sprintf("%d:%s", encryptedKey.CipherFunc, strings.ToUpper(hex.EncodeToString(encryptedKey.Key)))
+					b.logKeyEvent(req, keyEventAccessed, name)
 					return &logical.Response{
 						Data: map[string]interface{}{
 							"session_key": sessionKey,
@@ -127,5 +141,6 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 const pathDecryptSessionKeyHelpSyn = "Decrypt a session key of a message using a named GPG key"
 
 const pathDecryptSessionKeyHelpDesc = `
-This path uses the named GPG key from the request path to decrypt the session key of a message. 
+This path uses the named GPG key from the request path to decrypt the session key of a message.
+It requires the "update" capability.
 `
@@ -5,12 +5,15 @@ import (
 	"context"
 	"crypto"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
 	"golang.org/x/crypto/openpgp/packet"
 	"strings"
+	"time"
 )
 
 func pathSign(b *backend) *framework.Path {
@@ -18,16 +21,19 @@ func pathSign(b *backend) *framework.Path {
 		Pattern: "sign/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("urlalgorithm"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
+				Type:         framework.TypeString,
+				Description:  "The key to use",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"input": {
-				Type:        framework.TypeString,
-				Description: "The base64-encoded input data",
+				Type:         framework.TypeString,
+				Description:  "The base64-encoded input data",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Input", Group: "Input"},
 			},
 			"urlalgorithm": {
-				Type:        framework.TypeString,
-				Description: "Hash algorithm to use (POST URL parameter)",
+				Type:         framework.TypeString,
+				Description:  "Hash algorithm to use (POST URL parameter)",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Hash algorithm", Group: "Output"},
 			},
 			"algorithm": {
 				Type:    framework.TypeString,
@@ -40,11 +46,18 @@ func pathSign(b *backend) *framework.Path {
 * sha2-512
 
 Defaults to "sha2-256".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Hash algorithm", Group: "Output"},
 			},
 			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Type:         framework.TypeString,
+				Default:      "base64",
+				Description:  `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Format", Group: "Output"},
+			},
+			"time_override": {
+				Type:         framework.TypeString,
+				Description:  "RFC 3339 time used instead of the current time in the signature. Only accepted when debug_mode is enabled in the backend configuration.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Time override", Group: "Output"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -62,21 +75,25 @@ func pathVerify(b *backend) *framework.Path {
 		Pattern: "verify/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
+				Type:         framework.TypeString,
+				Description:  "The key to use",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Key name", Group: "Key Settings"},
 			},
 			"input": {
-				Type:        framework.TypeString,
-				Description: "The base64-encoded input data to verify",
+				Type:         framework.TypeString,
+				Description:  "The base64-encoded input data to verify",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Input", Group: "Input"},
 			},
 			"signature": {
-				Type:        framework.TypeString,
-				Description: "The signature",
+				Type:         framework.TypeString,
+				Description:  "The signature",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Signature", Group: "Input"},
 			},
 			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format the signature use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Type:         framework.TypeString,
+				Default:      "base64",
+				Description:  `Encoding format the signature use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Format", Group: "Output"},
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -89,11 +106,41 @@ func pathVerify(b *backend) *framework.Path {
 	}
 }
 
+func pathVerifyClearsign(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "verify-clearsign/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"clearsigned_text": {
+				Type:         framework.TypeString,
+				Description:  "The clearsigned text to verify",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Clearsigned text", Group: "Input"},
+			},
+			"signer_key_name": {
+				Type:         framework.TypeString,
+				Description:  "The name of the stored key of the signer. Exclusive with signer_key.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Signer key name", Group: "Key Settings"},
+			},
+			"signer_key": {
+				Type:         framework.TypeString,
+				Description:  "The ASCII-armored GPG key of the signer. Exclusive with signer_key_name.",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Signer key", Group: "Key Settings"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathVerifyClearsignWrite,
+			},
+		},
+		HelpSynopsis:    pathVerifyClearsignHelpSyn,
+		HelpDescription: pathVerifyClearsignHelpDesc,
+	}
+}
+
 func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	inputB64 := data.Get("input").(string)
-	input, err := base64.StdEncoding.DecodeString(inputB64)
+	input, err := decodeBase64("input", inputB64)
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	config := packet.Config{}
@@ -115,6 +162,21 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
 
+	backendConfig, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if !algorithmAllowed(backendConfig, algorithm) {
+		return logical.ErrorResponse("algorithm not permitted by policy"), logical.ErrInvalidRequest
+	}
+	// The time override only applies to the signature, the key is checked
+	// against the current time
+	now := time.Now()
+	config.Time, err = timeOverride(backendConfig, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	format := data.Get("format").(string)
 	switch format {
 	case "base64":
@@ -134,18 +196,31 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data
 	if err != nil {
 		return nil, err
 	}
+	if entity.PrivateKey == nil {
+		return logical.ErrorResponse("key is public-key-only; cannot perform private key operations"), logical.ErrInvalidRequest
+	}
+	if keyExpired(entity, now) {
+		return logical.ErrorResponse(errKeyExpired), logical.ErrInvalidRequest
+	}
+	signingPrivateKey, ok := signingKey(entity, now)
+	if !ok {
+		return logical.ErrorResponse("key has no signing subkey"), logical.ErrInvalidRequest
+	}
+	// Detached signatures are always issued by the private key of the entity,
+	// which is set to the selected signing key
+	signer := &openpgp.Entity{PrimaryKey: &signingPrivateKey.PublicKey, PrivateKey: signingPrivateKey}
 
 	message := bytes.NewReader(input)
 	var signature bytes.Buffer
 	switch format {
 	case "ascii-armor":
-		err = openpgp.ArmoredDetachSign(&signature, entity, message, &config)
+		err = openpgp.ArmoredDetachSign(&signature, signer, message, &config)
 		if err != nil {
 			return nil, err
 		}
 	case "base64":
 		encoder := base64.NewEncoder(base64.StdEncoding, &signature)
-		err = openpgp.DetachSign(encoder, entity, message, &config)
+		err = openpgp.DetachSign(encoder, signer, message, &config)
 		if err != nil {
 			return nil, err
 		}
@@ -155,18 +230,28 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data
 		}
 	}
 
-	return &logical.Response{
+	keyID, err := firstPacketKeyID(signature.Bytes(), format)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"signature": signature.String(),
+			"key_id":    fmt.Sprintf("%016x", keyID),
 		},
-	}, nil
+	}
+	addKeyIdentifiers(resp.Data, publicKeyByID(entity, keyID))
+	resp.Warnings = keyWarnings(entity, now)
+	b.logKeyEvent(req, keyEventAccessed, data.Get("name").(string))
+	return resp, nil
 }
 
 func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	inputB64 := data.Get("input").(string)
-	input, err := base64.StdEncoding.DecodeString(inputB64)
+	input, err := decodeBase64("input", inputB64)
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	format := data.Get("format").(string)
@@ -177,11 +262,13 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, dat
 		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
 	}
 
-	keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	name := data.Get("name").(string)
+	keyEntry, err := b.key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if keyEntry == nil {
+		b.logOperationFailure(req, "verification", name, "key_not_found")
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
 
@@ -200,17 +287,150 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, dat
 	case "ascii-armor":
 		_, err = openpgp.CheckArmoredDetachedSignature(keyring, message, signature)
 	}
+	if err != nil {
+		b.logOperationFailure(req, "verification", name, "invalid_signature")
+	}
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"valid": err == nil,
 		},
 	}
+	if err == nil {
+		resp.Warnings = keyWarnings(keyring[0], time.Now())
+		if p, err := firstPacket([]byte(data.Get("signature").(string)), format); err == nil {
+			if sig, ok := p.(*packet.Signature); ok {
+				resp.Warnings = append(resp.Warnings, signatureHashWarnings(sig.Hash)...)
+			}
+		}
+	}
 
 	return resp, nil
 }
 
+// hasSigningKey reports whether the entity holds a non-expired private key that
+// can be used to sign a message, either a subkey or the primary key.
+func hasSigningKey(e *openpgp.Entity, now time.Time) bool {
+	_, ok := signingKey(e, now)
+	return ok
+}
+
+// signingKey returns the private key signing the messages of the entity, the
+// first non-expired and non-revoked signing subkey or else the primary key if
+// its primary identity allows it to sign.
+func signingKey(e *openpgp.Entity, now time.Time) (*packet.PrivateKey, bool) {
+	for _, subkey := range e.Subkeys {
+		if subkey.PrivateKey == nil || subkey.Sig.SigType == packet.SigTypeSubkeyRevocation {
+			continue
+		}
+		if subkey.Sig.FlagsValid && subkey.Sig.FlagSign && subkey.PublicKey.PubKeyAlgo.CanSign() && !subkey.Sig.KeyExpired(now) {
+			return subkey.PrivateKey, true
+		}
+	}
+
+	if e.PrivateKey == nil || !e.PrimaryKey.PubKeyAlgo.CanSign() {
+		return nil, false
+	}
+	ident := primaryIdentity(e)
+	if ident == nil {
+		return nil, false
+	}
+	sig := ident.SelfSignature
+	if !sig.FlagsValid || sig.FlagSign && !sig.KeyExpired(now) {
+		return e.PrivateKey, true
+	}
+	return nil, false
+}
+
+// primaryIdentity returns the identity flagged as primary, or else any
+// identity of the entity, as the openpgp package does.
+func primaryIdentity(e *openpgp.Entity) *openpgp.Identity {
+	var first *openpgp.Identity
+	for _, ident := range e.Identities {
+		if first == nil {
+			first = ident
+		}
+		if ident.SelfSignature.IsPrimaryId != nil && *ident.SelfSignature.IsPrimaryId {
+			return ident
+		}
+	}
+	return first
+}
+
+func (b *backend) pathVerifyClearsignWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	signerKeyName := data.Get("signer_key_name").(string)
+	signerKey := data.Get("signer_key").(string)
+
+	var keyring openpgp.EntityList
+	switch {
+	case signerKeyName != "" && signerKey != "":
+		return logical.ErrorResponse("only one of signer_key_name and signer_key can be provided"), logical.ErrInvalidRequest
+	case signerKeyName != "":
+		keyEntry, err := b.key(ctx, req.Storage, signerKeyName)
+		if err != nil {
+			return nil, err
+		}
+		if keyEntry == nil {
+			b.logOperationFailure(req, "verification", signerKeyName, "key_not_found")
+			return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+		}
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(keyEntry.SerializedKey))
+		if err != nil {
+			return nil, err
+		}
+	case signerKey != "":
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(signerKey))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		keyring = el
+	default:
+		return logical.ErrorResponse("one of signer_key_name or signer_key is required"), logical.ErrInvalidRequest
+	}
+
+	block, _ := clearsign.Decode([]byte(data.Get("clearsigned_text").(string)))
+	if block == nil {
+		b.logOperationFailure(req, "verification", signerKeyName, "invalid_encoding")
+		return logical.ErrorResponse("unable to decode the clearsigned text"), logical.ErrInvalidRequest
+	}
+
+	signerFingerprint := ""
+	var signerPublicKey *packet.PublicKey
+	signer, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body)
+	if err != nil {
+		b.logOperationFailure(req, "verification", signerKeyName, "invalid_signature")
+	} else {
+		signerFingerprint = hex.EncodeToString(signer.PrimaryKey.Fingerprint[:])
+		signerPublicKey = signer.PrimaryKey
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"valid":              err == nil,
+			"signer_fingerprint": signerFingerprint,
+			"plaintext":          base64.StdEncoding.EncodeToString(block.Plaintext),
+		},
+	}
+	addKeyIdentifiers(resp.Data, signerPublicKey)
+	if signer != nil {
+		resp.Warnings = keyWarnings(signer, time.Now())
+	}
+	return resp, nil
+}
+
 const pathSignHelpSyn = "Generate a signature for input data using the named GPG key"
-const pathSignHelpDesc = "Generates a signature of the input data using the named GPG key."
+const pathSignHelpDesc = `
+Generates a signature of the input data using the named GPG key.
+It requires the "update" capability.
+`
 const pathVerifyHelpSyn = "Verify a signature for input data created using the named GPG key"
-const pathVerifyHelpDesc = "Verifies a signature of the input data using the named GPG key."
+const pathVerifyHelpDesc = `
+Verifies a signature of the input data using the named GPG key.
+It requires the "update" capability.
+`
+const pathVerifyClearsignHelpSyn = "Verify a clearsigned text"
+const pathVerifyClearsignHelpDesc = `
+Verifies the signature of a clearsigned text using a named GPG key or
+the provided GPG key of the signer. The plaintext is returned base64 encoded.
+It requires the "update" capability.
+`
@@ -0,0 +1,97 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathWKD(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "wkd/(?P<email_hash>[" + zbase32Alphabet + "]{32})",
+		Fields: map[string]*framework.FieldSchema{
+			"email_hash": {
+				Type:         framework.TypeString,
+				Description:  "Z-Base-32 encoded SHA-1 hash of the lowercased local part of the email",
+				DisplayAttrs: &framework.DisplayAttributes{Name: "Email hash", Group: "Input"},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathWKDRead,
+			},
+		},
+		HelpSynopsis:    pathWKDHelpSyn,
+		HelpDescription: pathWKDHelpDesc,
+	}
+}
+
+func (b *backend) pathWKDRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	emailHash := data.Get("email_hash").(string)
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.WKDDomain == "" {
+		return logical.ErrorResponse("the Web Key Directory is disabled"), logical.ErrUnsupportedPath
+	}
+
+	names, err := req.Storage.List(ctx, "key/")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		entry, err := b.key(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		// A corrupted key must not prevent serving the other ones
+		entity, err := b.entity(entry)
+		if err != nil {
+			b.Logger().Warn("skipping the key from the Web Key Directory", "key_name", name)
+			continue
+		}
+		for _, ident := range entity.Identities {
+			localPart, domain, err := splitEmail(ident.UserId.Email)
+			if err != nil || domain != config.WKDDomain || wkdHash(localPart) != emailHash {
+				continue
+			}
+			if err := entity.Serialize(&buf); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if buf.Len() == 0 {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/octet-stream",
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+const pathWKDHelpSyn = "Serve the public keys using the Web Key Directory protocol"
+
+const pathWKDHelpDesc = `
+This path serves the binary public keys of the named GPG keys having an
+identity whose email matches the WKD hash of the request path and the
+wkd_domain of the backend configuration. Mounting the backend behind the
+/.well-known/openpgpkey/hu/ path of this domain makes it a Web Key
+Directory. This path is unauthenticated and disabled unless wkd_domain is
+set.
+`
@@ -0,0 +1,100 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestGPG_WKDServeKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	createKey := func(name, email string) {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"real_name": "Vault GPG test",
+				"email":     email,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	createKey("test", "Vault@example.com")
+	createKey("test2", "other@example.com")
+	// Same local part in another domain
+	createKey("test3", "vault@example.org")
+	// The stored keys that cannot be parsed are skipped
+	entry, err := logical.StorageEntryJSON("key/broken", &keyEntry{SerializedKey: []byte("broken")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "wkd/" + wkdHash("vault"),
+		})
+	}
+
+	// The Web Key Directory is disabled by default
+	resp, err := read()
+	if err != logical.ErrUnsupportedPath || !resp.IsError() {
+		t.Fatalf("expected the Web Key Directory to be disabled, got: %#v, %v", resp, err)
+	}
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"wkd_domain": "example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() {
+		t.Fatalf("not expected response: %#v", resp)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/octet-stream" {
+		t.Fatalf("not expected content type: %s", resp.Data[logical.HTTPContentType])
+	}
+	el, err := openpgp.ReadKeyRing(bytes.NewReader(resp.Data[logical.HTTPRawBody].([]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el) != 1 {
+		t.Fatalf("1 entity is expected, %d found", len(el))
+	}
+	if el[0].PrivateKey != nil {
+		t.Fatal("private key should not be served")
+	}
+	for _, ident := range el[0].Identities {
+		if ident.UserId.Email != "Vault@example.com" {
+			t.Fatalf("not expected identity: %s", ident.Name)
+		}
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "wkd/" + wkdHash("unknown"),
+	})
+	if !(resp == nil && err == nil) {
+		t.Fatal("No key matches the hash but does not return not found")
+	}
+}
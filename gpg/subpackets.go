@@ -0,0 +1,142 @@
+package gpg
+
+import (
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// Signature subpacket types and hash identifier from RFC 4880, section 5.2.3.1
+// and 9.4, and RFC 4880bis for the issuer fingerprint. The packet library does
+// not export them.
+const (
+	creationTimeSubpacket        = 2
+	signatureExpirationSubpacket = 3
+	trustSignatureSubpacket      = 5
+	keyExpirationSubpacket       = 9
+	prefSymmetricAlgosSubpacket  = 11
+	issuerSubpacket              = 16
+	prefHashAlgosSubpacket       = 21
+	prefCompressionSubpacket     = 22
+	primaryUserIDSubpacket       = 25
+	keyFlagsSubpacket            = 27
+	issuerFingerprintSubpacket   = 33
+	hashIDSHA256                 = 8
+)
+
+// Key flags from RFC 4880, section 5.2.3.21. The authentication flag is not
+// parsed by the packet library.
+const (
+	keyFlagCertify            = 0x01
+	keyFlagSign               = 0x02
+	keyFlagEncryptCommunicate = 0x04
+	keyFlagEncryptStorage     = 0x08
+	keyFlagAuthenticate       = 0x20
+)
+
+// signatureSubpacket is a subpacket of the hashed area of a signature, its type
+// stripped of the critical bit.
+type signatureSubpacket struct {
+	subpacketType byte
+	contents      []byte
+}
+
+// hashedSubpackets returns the subpackets found in the hashed area of the
+// signature, including the ones the packet library does not parse.
+func hashedSubpackets(sig *packet.Signature) ([]signatureSubpacket, bool) {
+	// Version, type, algorithms and length of the hashed area precede it
+	if len(sig.HashSuffix) < 6 {
+		return nil, false
+	}
+	hashedLen := int(sig.HashSuffix[4])<<8 | int(sig.HashSuffix[5])
+	if len(sig.HashSuffix) < 6+hashedLen {
+		return nil, false
+	}
+	subpackets := sig.HashSuffix[6 : 6+hashedLen]
+
+	// RFC 4880, section 5.2.3.1
+	var result []signatureSubpacket
+	for len(subpackets) > 0 {
+		var length int
+		switch {
+		case subpackets[0] < 192:
+			length = int(subpackets[0])
+			subpackets = subpackets[1:]
+		case subpackets[0] < 255:
+			if len(subpackets) < 2 {
+				return nil, false
+			}
+			length = (int(subpackets[0])-192)<<8 + int(subpackets[1]) + 192
+			subpackets = subpackets[2:]
+		default:
+			if len(subpackets) < 5 {
+				return nil, false
+			}
+			length = int(subpackets[1])<<24 | int(subpackets[2])<<16 | int(subpackets[3])<<8 | int(subpackets[4])
+			subpackets = subpackets[5:]
+		}
+		if length < 1 || length > len(subpackets) {
+			return nil, false
+		}
+		result = append(result, signatureSubpacket{
+			subpacketType: subpackets[0] & 0x7f,
+			contents:      subpackets[1:length],
+		})
+		subpackets = subpackets[length:]
+	}
+	return result, true
+}
+
+// signatureKeyFlags returns the first octet of the key flags subpacket found in
+// the hashed area of the signature.
+func signatureKeyFlags(sig *packet.Signature) (byte, bool) {
+	subpackets, ok := hashedSubpackets(sig)
+	if !ok {
+		return 0, false
+	}
+	for _, subpacket := range subpackets {
+		if subpacket.subpacketType == keyFlagsSubpacket && len(subpacket.contents) > 0 {
+			return subpacket.contents[0], true
+		}
+	}
+	return 0, false
+}
+
+// resignableSubpackets are the subpacket types written again by the packet
+// library when a signature is signed again. The issuer fingerprint is not
+// written but it only repeats the issuer key ID.
+var resignableSubpackets = map[byte]bool{
+	creationTimeSubpacket:        true,
+	signatureExpirationSubpacket: true,
+	keyExpirationSubpacket:       true,
+	prefSymmetricAlgosSubpacket:  true,
+	issuerSubpacket:              true,
+	prefHashAlgosSubpacket:       true,
+	prefCompressionSubpacket:     true,
+	primaryUserIDSubpacket:       true,
+	keyFlagsSubpacket:            true,
+	issuerFingerprintSubpacket:   true,
+}
+
+// canSignAgain reports whether the hashed area of the signature is kept when it
+// is signed again by the packet library. Notations, unknown subpackets and key
+// flags the library does not parse, such as the authentication flag, would be
+// dropped.
+func canSignAgain(sig *packet.Signature) bool {
+	subpackets, ok := hashedSubpackets(sig)
+	if !ok {
+		return false
+	}
+	for _, subpacket := range subpackets {
+		if !resignableSubpackets[subpacket.subpacketType] {
+			return false
+		}
+		if subpacket.subpacketType == keyFlagsSubpacket {
+			if len(subpacket.contents) != 1 {
+				return false
+			}
+			if subpacket.contents[0]&^(keyFlagCertify|keyFlagSign|keyFlagEncryptCommunicate|keyFlagEncryptStorage) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
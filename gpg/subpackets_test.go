@@ -0,0 +1,53 @@
+package gpg
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGPG_SignatureKeyFlags(t *testing.T) {
+	cases := []struct {
+		hashSuffix []byte
+		flags      byte
+		found      bool
+	}{
+		// Creation time and key flags with the authentication flag
+		{[]byte{4, 0x13, 1, 8, 0, 9, 5, 2, 0, 0, 0, 0, 2, 27, 0x21}, 0x21, true},
+		// Critical key flags subpacket
+		{[]byte{4, 0x13, 1, 8, 0, 3, 2, 0x80 | 27, 0x03}, 0x03, true},
+		// Creation time only
+		{[]byte{4, 0x13, 1, 8, 0, 6, 5, 2, 0, 0, 0, 0}, 0, false},
+		// Truncated subpacket
+		{[]byte{4, 0x13, 1, 8, 0, 2, 5, 2}, 0, false},
+		{nil, 0, false},
+	}
+	for i, c := range cases {
+		flags, found := signatureKeyFlags(&packet.Signature{HashSuffix: c.hashSuffix})
+		if flags != c.flags || found != c.found {
+			t.Fatalf("case %d: expected %#x, %t, got %#x, %t", i, c.flags, c.found, flags, found)
+		}
+	}
+}
+
+func TestGPG_CanSignAgain(t *testing.T) {
+	issuerFingerprint := append([]byte{22, 33, 4}, make([]byte, 20)...)
+	cases := []struct {
+		hashSuffix []byte
+		expected   bool
+	}{
+		// Creation time, encryption key flags and issuer fingerprint, as written by GnuPG
+		{append([]byte{4, 0x18, 1, 8, 0, 32, 5, 2, 0, 0, 0, 0, 2, 27, 0x0c}, issuerFingerprint...), true},
+		// Authentication key flag
+		{[]byte{4, 0x18, 1, 8, 0, 9, 5, 2, 0, 0, 0, 0, 2, 27, 0x20}, false},
+		// Notation
+		{[]byte{4, 0x18, 1, 8, 0, 12, 5, 2, 0, 0, 0, 0, 5, 20, 0, 0, 0, 0}, false},
+		// Truncated subpacket
+		{[]byte{4, 0x18, 1, 8, 0, 2, 5, 2}, false},
+	}
+	for i, c := range cases {
+		if actual := canSignAgain(&packet.Signature{HashSuffix: c.hashSuffix}); actual != c.expected {
+			t.Fatalf("case %d: expected %t, got %t", i, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gpg
+
+import (
+	"crypto"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// keyExpiryWarningPeriod is how long before its expiration the use of a key is
+// reported in the warnings of the responses.
+const keyExpiryWarningPeriod = 30 * 24 * time.Hour
+
+const minRecommendedRSABits = 2048
+
+var deprecatedHashes = map[crypto.Hash]string{
+	crypto.MD5:       "md5",
+	crypto.SHA1:      "sha1",
+	crypto.RIPEMD160: "ripemd160",
+}
+
+// keyWarnings returns the warnings about the use of the primary key of the
+// entity: a deprecated algorithm, a too small RSA modulus or an expiration
+// closer than keyExpiryWarningPeriod.
+func keyWarnings(e *openpgp.Entity, now time.Time) []string {
+	var warnings []string
+
+	pk := e.PrimaryKey
+	switch pk.PubKeyAlgo {
+	case packet.PubKeyAlgoDSA, packet.PubKeyAlgoElGamal:
+		warnings = append(warnings, fmt.Sprintf("key %016x uses the deprecated %s algorithm", pk.KeyId, recipientKeyAlgorithms[pk.PubKeyAlgo]))
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		if bits, err := pk.BitLength(); err == nil && bits < minRecommendedRSABits {
+			warnings = append(warnings, fmt.Sprintf("key %016x uses a deprecated %d bits RSA modulus, at least %d bits are recommended", pk.KeyId, bits, minRecommendedRSABits))
+		}
+	}
+
+	if expiration, ok := keyExpiration(e); ok && expiration.After(now) && expiration.Sub(now) < keyExpiryWarningPeriod {
+		warnings = append(warnings, fmt.Sprintf("key %016x expires in %s, on %s", pk.KeyId, expiration.Sub(now).Truncate(time.Minute), expiration.UTC().Format(time.RFC3339)))
+	}
+
+	return warnings
+}
+
+// keyExpiration returns the expiration time of the primary key of the entity,
+// set by the self-signature of its primary identity as the openpgp package
+// does.
+func keyExpiration(e *openpgp.Entity) (time.Time, bool) {
+	ident := primaryIdentity(e)
+	if ident == nil {
+		return time.Time{}, false
+	}
+	sig := ident.SelfSignature
+	if sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+	return e.PrimaryKey.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second), true
+}
+
+// keyExpired reports whether the primary key of the entity has expired.
+func keyExpired(e *openpgp.Entity, now time.Time) bool {
+	expiration, ok := keyExpiration(e)
+	return ok && !expiration.After(now)
+}
+
+// signatureHashWarnings returns a warning when the signature uses a deprecated
+// hash algorithm.
+func signatureHashWarnings(hash crypto.Hash) []string {
+	name, ok := deprecatedHashes[hash]
+	if !ok {
+		return nil
+	}
+	return []string{fmt.Sprintf("signature uses the deprecated %s hash algorithm", name)}
+}
@@ -0,0 +1,178 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGPG_KeyWarnings(t *testing.T) {
+	now := time.Now()
+
+	small, err := openpgp.NewEntity("Small", "", "small@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := keyWarnings(small, now)
+	expected := fmt.Sprintf("key %016x uses a deprecated 1024 bits RSA modulus, at least 2048 bits are recommended", small.PrimaryKey.KeyId)
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Fatalf("expected warning %q, got: %#v", expected, warnings)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := el[0]
+	if warnings := keyWarnings(entity, now); len(warnings) != 0 {
+		t.Fatalf("not expected warnings: %#v", warnings)
+	}
+
+	lifetime := uint32(now.Add(10*24*time.Hour).Sub(entity.PrimaryKey.CreationTime) / time.Second)
+	for _, ident := range entity.Identities {
+		ident.SelfSignature.KeyLifetimeSecs = &lifetime
+	}
+	warnings = keyWarnings(entity, now)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "key 2f7b5633b6f42527 expires in ") {
+		t.Fatalf("expected expiration warning, got: %#v", warnings)
+	}
+	// Expired keys are not about to expire
+	if warnings := keyWarnings(entity, now.Add(20*24*time.Hour)); len(warnings) != 0 {
+		t.Fatalf("not expected warnings: %#v", warnings)
+	}
+	// Nor the keys expiring later than the warning period
+	if warnings := keyWarnings(entity, now.Add(-30*24*time.Hour)); len(warnings) != 0 {
+		t.Fatalf("not expected warnings: %#v", warnings)
+	}
+
+	// Only the lifetime of the primary identity is considered
+	multiple, err := openpgp.NewEntity("Primary", "", "primary@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := uint32(1)
+	uid := packet.NewUserId("Other", "", "other@example.com")
+	multiple.Identities[uid.Id] = &openpgp.Identity{
+		Name:   uid.Id,
+		UserId: uid,
+		SelfSignature: &packet.Signature{
+			CreationTime:    now,
+			SigType:         packet.SigTypePositiveCert,
+			PubKeyAlgo:      packet.PubKeyAlgoRSA,
+			Hash:            crypto.SHA256,
+			KeyLifetimeSecs: &expired,
+			IssuerKeyId:     &multiple.PrimaryKey.KeyId,
+		},
+	}
+	for i := 0; i < 10; i++ {
+		if keyExpired(multiple, now.Add(time.Hour)) {
+			t.Fatal("expected the key to not expire without lifetime on its primary identity")
+		}
+	}
+}
+
+func TestGPG_SignatureHashWarnings(t *testing.T) {
+	for hash, expected := range map[crypto.Hash][]string{
+		crypto.SHA1:      {"signature uses the deprecated sha1 hash algorithm"},
+		crypto.RIPEMD160: {"signature uses the deprecated ripemd160 hash algorithm"},
+		crypto.SHA256:    nil,
+	} {
+		warnings := signatureHashWarnings(hash)
+		if fmt.Sprint(warnings) != fmt.Sprint(expected) {
+			t.Fatalf("%s: expected warnings %#v, got: %#v", hash, expected, warnings)
+		}
+	}
+}
+
+func TestGPG_ResponseWarnings(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	small, err := openpgp.NewEntity("Small", "", "small@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/small",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      testArmoredPrivateKey(t, small),
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "keys/test"
+	req.Data = map[string]interface{}{
+		"generate": false,
+		"key":      gpgKey,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	smallKeyWarning := fmt.Sprintf("key %016x uses a deprecated 1024 bits RSA modulus, at least 2048 bits are recommended", small.PrimaryKey.KeyId)
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("%s: not expected error response: %#v", path, *resp)
+		}
+		return resp
+	}
+	assertWarnings := func(resp *logical.Response, expected ...string) {
+		if fmt.Sprint(resp.Warnings) != fmt.Sprint(expected) {
+			t.Fatalf("expected warnings %#v, got: %#v", expected, resp.Warnings)
+		}
+	}
+
+	assertWarnings(request(logical.ReadOperation, "keys/small", nil), smallKeyWarning)
+	assertWarnings(request(logical.ReadOperation, "keys/test", nil))
+
+	resp := request(logical.UpdateOperation, "sign/small", map[string]interface{}{"input": "QWxwYWNhcwo="})
+	assertWarnings(resp, smallKeyWarning)
+	assertWarnings(request(logical.UpdateOperation, "verify/small", map[string]interface{}{
+		"input":     "QWxwYWNhcwo=",
+		"signature": resp.Data["signature"],
+	}), smallKeyWarning)
+
+	assertWarnings(request(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"plaintext":     "QWxwYWNhcwo=",
+		"recipient_key": testPublicKey(t, b, storage, "small"),
+	}), smallKeyWarning)
+
+	// Signature using a deprecated hash algorithm
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signature bytes.Buffer
+	err = openpgp.DetachSign(&signature, el[0], strings.NewReader("Alpacas\n"), &packet.Config{DefaultHash: crypto.SHA1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWarnings(request(logical.UpdateOperation, "verify/test", map[string]interface{}{
+		"input":     "QWxwYWNhcwo=",
+		"signature": base64.StdEncoding.EncodeToString(signature.Bytes()),
+	}), "signature uses the deprecated sha1 hash algorithm")
+}
@@ -0,0 +1,175 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" // #nosec G505 SHA-1 is mandated by the Web Key Directory specification
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+const (
+	zbase32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+
+	wkdCacheTTL        = time.Hour
+	wkdCacheMaxEntries = 1024
+	wkdMaxKeySize      = 1 << 20
+	wkdHTTPTimeout     = 10 * time.Second
+)
+
+type wkdCacheEntry struct {
+	entity  *openpgp.Entity
+	expires time.Time
+}
+
+// zbase32Encode encodes the data using the human-oriented base-32 encoding
+// used by the Web Key Directory.
+func zbase32Encode(data []byte) string {
+	var sb strings.Builder
+	var buffer, bits uint
+	for _, b := range data {
+		buffer = buffer<<8 | uint(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			sb.WriteByte(zbase32Alphabet[(buffer>>bits)&0x1f])
+		}
+	}
+	if bits > 0 {
+		sb.WriteByte(zbase32Alphabet[(buffer<<(5-bits))&0x1f])
+	}
+	return sb.String()
+}
+
+// wkdHash returns the hashed local part of an email address as specified
+// by the Web Key Directory.
+func wkdHash(localPart string) string {
+	h := sha1.Sum([]byte(strings.ToLower(localPart))) // #nosec G401
+	return zbase32Encode(h[:])
+}
+
+func splitEmail(email string) (string, string, error) {
+	i := strings.LastIndex(email, "@")
+	if i <= 0 || i == len(email)-1 {
+		return "", "", fmt.Errorf("invalid email address %s", email)
+	}
+	return email[:i], strings.ToLower(email[i+1:]), nil
+}
+
+// wkdURLs returns the URLs of the advanced and direct methods of the Web Key
+// Directory, in the order they must be tried.
+func wkdURLs(email string) ([]string, error) {
+	localPart, domain, err := splitEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	hash := wkdHash(localPart)
+	query := url.Values{"l": {localPart}}.Encode()
+	return []string{
+		fmt.Sprintf("https://openpgpkey.%s/.well-known/openpgpkey/%s/hu/%s?%s", domain, domain, hash, query),
+		fmt.Sprintf("https://%s/.well-known/openpgpkey/hu/%s?%s", domain, hash, query),
+	}, nil
+}
+
+// wkdLookup discovers the key of the recipient using the Web Key Directory.
+// Successful lookups are cached.
+func (b *backend) wkdLookup(ctx context.Context, email string) (*openpgp.Entity, error) {
+	email = strings.ToLower(email)
+
+	b.wkdCacheLock.RLock()
+	cached, ok := b.wkdCache[email]
+	b.wkdCacheLock.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.entity, nil
+	}
+
+	urls, err := wkdURLs(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, u := range urls {
+		entity, err := b.wkdFetch(ctx, u, email)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		b.wkdCacheStore(email, entity)
+		return entity, nil
+	}
+
+	return nil, fmt.Errorf("no key found for %s using WKD: %w", email, lastErr)
+}
+
+// wkdCacheStore caches the key of the recipient. The expired entries are
+// evicted when the cache is full, and an arbitrary one when none has expired,
+// so that the emails provided by the callers cannot grow it without limit.
+func (b *backend) wkdCacheStore(email string, entity *openpgp.Entity) {
+	b.wkdCacheLock.Lock()
+	defer b.wkdCacheLock.Unlock()
+
+	now := time.Now()
+	if _, ok := b.wkdCache[email]; !ok && len(b.wkdCache) >= wkdCacheMaxEntries {
+		for cachedEmail, cached := range b.wkdCache {
+			if !now.Before(cached.expires) {
+				delete(b.wkdCache, cachedEmail)
+			}
+		}
+		for cachedEmail := range b.wkdCache {
+			if len(b.wkdCache) < wkdCacheMaxEntries {
+				break
+			}
+			delete(b.wkdCache, cachedEmail)
+		}
+	}
+	b.wkdCache[email] = wkdCacheEntry{entity: entity, expires: now.Add(wkdCacheTTL)}
+}
+
+// wkdFetch returns the key published at the URL having a user ID matching the
+// email, as required by the Web Key Directory specification.
+func (b *backend) wkdFetch(ctx context.Context, u, email string) (*openpgp.Entity, error) {
+	ctx, cancel := context.WithTimeout(ctx, wkdHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.wkdClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+
+	key, err := ioutil.ReadAll(io.LimitReader(resp.Body, wkdMaxKeySize))
+	if err != nil {
+		return nil, err
+	}
+	el, err := openpgp.ReadKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(el) == 0 {
+		return nil, errNoKeyFound
+	}
+	for _, entity := range el {
+		for _, ident := range entity.Identities {
+			if strings.EqualFold(ident.UserId.Email, email) {
+				return entity, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no key from %s has a user ID matching %s", u, email)
+}
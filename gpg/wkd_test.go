@@ -0,0 +1,170 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestGPG_WKDHash(t *testing.T) {
+	// Test vector from the Web Key Directory specification
+	if hash := wkdHash("Joe.Doe"); hash != "iy9q119eutrkn8s1mk4r39qejnbu3n5q" {
+		t.Fatalf("not expected hash: %s", hash)
+	}
+
+	urls, err := wkdURLs("Joe.Doe@Example.ORG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"https://openpgpkey.example.org/.well-known/openpgpkey/example.org/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+		"https://example.org/.well-known/openpgpkey/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+	}
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Fatalf("expected URL %s, got: %s", expected[i], urls[i])
+		}
+	}
+
+	for _, email := range []string{"", "joe.doe", "@example.org", "joe.doe@"} {
+		if _, err := wkdURLs(email); err == nil {
+			t.Fatalf("expected %q to be rejected", email)
+		}
+	}
+}
+
+func TestGPG_EncryptWKDLookup(t *testing.T) {
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recipientKey bytes.Buffer
+	if err = el[0].Serialize(&recipientKey); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Host != "example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		switch r.URL.Path {
+		case "/.well-known/openpgpkey/hu/" + wkdHash("vault"):
+			w.Write(recipientKey.Bytes())
+		// The key of another address is published
+		case "/.well-known/openpgpkey/hu/" + wkdHash("other"):
+			w.Write(recipientKey.Bytes())
+		// No key is published
+		case "/.well-known/openpgpkey/hu/" + wkdHash("empty"):
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+	b.wkdClient = server.Client()
+	b.wkdClient.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(email string, errExpected bool) {
+		resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":       "QWxwYWNhcwo=",
+				"recipient_email": email,
+			},
+		})
+		if errExpected {
+			if !resp.IsError() {
+				t.Fatalf("expected error response: %#v", resp)
+			}
+			return
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+	}
+
+	// WKD lookup is disabled by default
+	encrypt("vault@example.com", true)
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("no request expected when WKD lookup is disabled, got %d", atomic.LoadInt32(&requests))
+	}
+
+	req.Path = "config"
+	req.Data = map[string]interface{}{
+		"enable_wkd_lookup": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Advanced method fails, direct method succeeds
+	encrypt("vault@example.com", false)
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", atomic.LoadInt32(&requests))
+	}
+
+	// Key is cached
+	encrypt("Vault@Example.com", false)
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expected the key to be cached, got %d requests", atomic.LoadInt32(&requests))
+	}
+
+	encrypt("unknown@example.com", true)
+	encrypt("other@example.com", true)
+	encrypt("empty@example.com", true)
+	encrypt("not an email", true)
+}
+
+func TestGPG_WKDCacheIsBounded(t *testing.T) {
+	b := Backend()
+	entity := &openpgp.Entity{}
+
+	b.wkdCacheStore("expired@example.com", entity)
+	b.wkdCache["expired@example.com"] = wkdCacheEntry{entity: entity, expires: time.Now().Add(-time.Second)}
+	for i := 0; i < 2*wkdCacheMaxEntries; i++ {
+		b.wkdCacheStore(fmt.Sprintf("user%d@example.com", i), entity)
+	}
+	if len(b.wkdCache) != wkdCacheMaxEntries {
+		t.Fatalf("expected %d cached keys, got %d", wkdCacheMaxEntries, len(b.wkdCache))
+	}
+	if _, ok := b.wkdCache["expired@example.com"]; ok {
+		t.Fatal("expected the expired key to be evicted")
+	}
+	if _, ok := b.wkdCache[fmt.Sprintf("user%d@example.com", 2*wkdCacheMaxEntries-1)]; !ok {
+		t.Fatal("expected the last key to be cached")
+	}
+}
@@ -20,6 +20,12 @@ BUILD_TAGS="${BUILD_TAGS}:-${TOOL}"
 GIT_COMMIT="$(git rev-parse HEAD)"
 GIT_DIRTY="$(test -n "`git status --porcelain`" && echo "+CHANGES" || true)"
 
+# Get the version from the tag of the commit, unless provided
+VERSION=${VERSION:-$(git describe --tags --exact-match 2>/dev/null || echo dev)}
+
+# Get the build date
+BUILD_DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
 # Determine the arch/os combos we're building for
 XC_ARCH=${XC_ARCH:-"386 amd64"}
 XC_OS=${XC_OS:-linux darwin windows freebsd openbsd netbsd solaris}
@@ -50,7 +56,7 @@ fi
 echo "==> Building..."
 gox \
     -osarch="${XC_OSARCH}" \
-    -ldflags "-X github.com/HAECHI-LABS/${TOOL}/version.GitCommit='${GIT_COMMIT}${GIT_DIRTY}'" \
+    -ldflags "-X github.com/HAECHI-LABS/${TOOL}/version.Version='${VERSION}' -X github.com/HAECHI-LABS/${TOOL}/version.GitCommit='${GIT_COMMIT}${GIT_DIRTY}' -X github.com/HAECHI-LABS/${TOOL}/version.BuildDate='${BUILD_DATE}'" \
     -output "pkg/{{.OS}}_{{.Arch}}/${TOOL}" \
     -tags="${BUILD_TAGS}" \
     ./cmd
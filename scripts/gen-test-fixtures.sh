@@ -0,0 +1,28 @@
+#!/usr/bin/env bash
+#
+# Generates with GnuPG the ASCII-armored private keys used as test fixtures
+# from the batch files of the keygen directory. Existing fixtures are kept,
+# delete one to generate it again.
+
+set -e
+
+testdata=${1:-testdata}
+
+for batch in "${testdata}"/keygen/*.batch; do
+    name=$(basename "${batch}" .batch)
+    fixture="${testdata}/${name}.asc"
+    if [[ -f ${fixture} ]]; then
+        continue
+    fi
+    if ! command -v gpg >/dev/null; then
+        echo "gpg is required to generate ${fixture}" >&2
+        exit 1
+    fi
+
+    echo "==> Generating ${fixture}..."
+    homedir=$(mktemp -d)
+    gpg --homedir "${homedir}" --batch --quiet --gen-key "${batch}"
+    gpg --homedir "${homedir}" --batch --armor --export-secret-keys > "${fixture}"
+    gpgconf --homedir "${homedir}" --kill gpg-agent
+    rm -rf "${homedir}"
+done
@@ -0,0 +1,13 @@
+package version
+
+// Build metadata of the plugin, injected at build time with -ldflags.
+var (
+	// Version is the released version of the plugin
+	Version = "dev"
+
+	// GitCommit is the git commit the plugin has been built from
+	GitCommit string
+
+	// BuildDate is the UTC date the plugin has been built at
+	BuildDate string
+)